@@ -0,0 +1,140 @@
+// Package audit records one structured event per authorization decision.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+const redacted = "[REDACTED]"
+
+// Decisions recorded in an Event.
+const (
+	DecisionAllowed = "allowed"
+	DecisionPartial = "partial"
+	DecisionDenied  = "denied"
+	DecisionError   = "error"
+)
+
+// tokenPattern matches bearer tokens and OpenShift access tokens in free-form text.
+var tokenPattern = regexp.MustCompile(`(?i)(bearer\s+)\S+|sha256~[\w-]+`)
+
+// Event is the audit record of a single authorization decision.
+type Event struct {
+	Time              time.Time `json:"time"`
+	Subject           string    `json:"subject"`
+	Groups            []string  `json:"groups,omitempty"`
+	Tenant            string    `json:"tenant"`
+	Permission        string    `json:"permission"`
+	Resource          string    `json:"resource"`
+	Namespaces        []string  `json:"namespaces,omitempty"`
+	AllowedNamespaces []string  `json:"allowedNamespaces,omitempty"`
+	Decision          string    `json:"decision"`
+	CacheHit          bool      `json:"cacheHit"`
+	LatencySeconds    float64   `json:"latencySeconds"`
+	ErrorClass        string    `json:"errorClass,omitempty"`
+	Error             string    `json:"error,omitempty"`
+}
+
+// Sink writes audit events to their destination.
+type Sink interface {
+	Write(Event) error
+	Close() error
+}
+
+// Logger samples, redacts and forwards audit events to a Sink.
+type Logger struct {
+	sink       Sink
+	logger     log.Logger
+	sampleRate float64
+}
+
+// NewLogger returns a Logger forwarding events to the given sink. Events of
+// allowed decisions are kept with the given probability between 0 and 1,
+// while denied and failed decisions are always kept.
+func NewLogger(l log.Logger, sink Sink, sampleRate float64) *Logger {
+	return &Logger{sink: sink, logger: l, sampleRate: sampleRate}
+}
+
+// Log records the event, redacting the given secrets and any token found in its error.
+// It is a no-op on a nil Logger.
+func (l *Logger) Log(ev Event, secrets ...string) {
+	if l == nil {
+		return
+	}
+
+	if !l.sampled(ev) {
+		return
+	}
+
+	ev.Error = Redact(ev.Error, secrets...)
+
+	if err := l.sink.Write(ev); err != nil {
+		level.Warn(l.logger).Log("msg", "failed to write audit event", "err", err) //nolint:errcheck
+	}
+}
+
+// Close closes the underlying sink.
+func (l *Logger) Close() error {
+	return l.sink.Close() //nolint:wrapcheck
+}
+
+func (l *Logger) sampled(ev Event) bool {
+	switch ev.Decision {
+	case DecisionAllowed, DecisionPartial:
+		return l.sampleRate >= 1 || rand.Float64() < l.sampleRate //nolint:gosec
+	default:
+		return true
+	}
+}
+
+// Redact replaces the given secrets and any token found in the given text.
+func Redact(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+
+	return tokenPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if sub := tokenPattern.FindStringSubmatch(m); sub[1] != "" {
+			return sub[1] + redacted
+		}
+
+		return redacted
+	})
+}
+
+type writerSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewWriterSink returns a Sink writing events as JSON lines to w, e.g. os.Stderr.
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{enc: json.NewEncoder(w)}
+}
+
+func (s *writerSink) Write(ev Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.enc.Encode(ev); err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *writerSink) Close() error {
+	return nil
+}
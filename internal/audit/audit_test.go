@@ -0,0 +1,179 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memorySink struct {
+	events []Event
+}
+
+func (s *memorySink) Write(ev Event) error {
+	s.events = append(s.events, ev)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	return nil
+}
+
+func TestLogger_Sampling(t *testing.T) {
+	sink := &memorySink{}
+	l := NewLogger(log.NewNopLogger(), sink, 0)
+
+	for _, d := range []string{DecisionAllowed, DecisionPartial, DecisionDenied, DecisionError} {
+		l.Log(Event{Subject: "test-user", Decision: d})
+	}
+
+	require.Len(t, sink.events, 2)
+	require.Equal(t, DecisionDenied, sink.events[0].Decision)
+	require.Equal(t, DecisionError, sink.events[1].Decision)
+}
+
+func TestLogger_NilIsNoop(t *testing.T) {
+	var l *Logger
+	l.Log(Event{Decision: DecisionDenied})
+}
+
+func TestRedact(t *testing.T) {
+	tt := []struct {
+		desc    string
+		in      string
+		secrets []string
+		want    string
+	}{
+		{
+			desc:    "known secret",
+			in:      "token my-secret-token rejected",
+			secrets: []string{"my-secret-token"},
+			want:    "token [REDACTED] rejected",
+		},
+		{
+			desc: "bearer token",
+			in:   "Authorization: Bearer abc.def.ghi failed",
+			want: "Authorization: Bearer [REDACTED] failed",
+		},
+		{
+			desc: "openshift token",
+			in:   "token sha256~Ab-c_123 expired",
+			want: "token [REDACTED] expired",
+		},
+		{
+			desc: "nothing to redact",
+			in:   "cluster-wide SAR failed",
+			want: "cluster-wide SAR failed",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, Redact(tc.in, tc.secrets...))
+		})
+	}
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewLogger(log.NewNopLogger(), NewWriterSink(&buf), 1)
+	l.Log(Event{Subject: "test-user", Decision: DecisionError, Error: "Bearer secret rejected"}, "secret")
+
+	var ev Event
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &ev))
+	require.Equal(t, "test-user", ev.Subject)
+	require.Equal(t, "Bearer [REDACTED] rejected", ev.Error)
+}
+
+func TestFileSink_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	s, err := NewFileSink(path, 200, 2)
+	require.NoError(t, err)
+
+	for range 10 {
+		require.NoError(t, s.Write(Event{Subject: "test-user", Decision: DecisionDenied}))
+	}
+
+	require.NoError(t, s.Close())
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		b, err := os.ReadFile(p)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(b), 200)
+		require.True(t, strings.HasSuffix(string(b), "\n"))
+	}
+
+	require.NoFileExists(t, path+".3")
+}
+
+func TestWebhookSink(t *testing.T) {
+	received := make(chan Event, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var ev Event
+		require.NoError(t, json.Unmarshal(b, &ev))
+		received <- ev
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := NewWebhookSink(log.NewNopLogger(), srv.URL, srv.Client(), time.Second, 10)
+	require.NoError(t, s.Write(Event{Subject: "test-user", Decision: DecisionDenied}))
+	require.NoError(t, s.Close())
+
+	ev := <-received
+	require.Equal(t, "test-user", ev.Subject)
+}
+
+func TestWebhookSink_WriteDuringClose(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := NewWebhookSink(log.NewNopLogger(), srv.URL, srv.Client(), time.Second, 100)
+
+	var wg sync.WaitGroup
+
+	for range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range 50 {
+				err := s.Write(Event{Subject: "test-user", Decision: DecisionDenied})
+				if err != nil {
+					assert.True(t, errors.Is(err, errSinkClosed) || errors.Is(err, errQueueFull), err)
+				}
+			}
+		}()
+	}
+
+	require.NoError(t, s.Close())
+	wg.Wait()
+
+	require.ErrorIs(t, s.Write(Event{}), errSinkClosed)
+	require.NoError(t, s.Close())
+}
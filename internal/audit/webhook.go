@@ -0,0 +1,123 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+var (
+	errQueueFull        = errors.New("audit queue full")
+	errSinkClosed       = errors.New("audit sink closed")
+	errUnexpectedStatus = errors.New("unexpected status code")
+)
+
+type webhookSink struct {
+	url     string
+	client  *http.Client
+	logger  log.Logger
+	timeout time.Duration
+
+	// mu guards closing events, so that no Write sends to the closed channel.
+	mu     sync.RWMutex
+	closed bool
+	events chan Event
+	wg     sync.WaitGroup
+}
+
+// NewWebhookSink returns a Sink sending every event as a JSON POST request to url.
+// Events are queued and sent in the background, so that a slow receiver does not
+// delay decisions; events are dropped when more than queueSize are pending.
+func NewWebhookSink(l log.Logger, url string, client *http.Client, timeout time.Duration, queueSize int) Sink {
+	s := &webhookSink{
+		url:     url,
+		client:  client,
+		logger:  l,
+		timeout: timeout,
+		events:  make(chan Event, queueSize),
+	}
+
+	s.wg.Add(1)
+
+	go s.run()
+
+	return s
+}
+
+func (s *webhookSink) Write(ev Event) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return errSinkClosed
+	}
+
+	select {
+	case s.events <- ev:
+		return nil
+	default:
+		return errQueueFull
+	}
+}
+
+// Close stops accepting events and waits for the queued events to be sent.
+// Events written after Close are rejected.
+func (s *webhookSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	return nil
+}
+
+func (s *webhookSink) run() {
+	defer s.wg.Done()
+
+	for ev := range s.events {
+		if err := s.send(ev); err != nil {
+			level.Warn(s.logger).Log("msg", "failed to send audit event", "err", err) //nolint:errcheck
+		}
+	}
+}
+
+func (s *webhookSink) send(ev Event) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to create audit request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post audit event: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode/100 != 2 { //nolint:gomnd
+		return fmt.Errorf("%w: %d", errUnexpectedStatus, res.StatusCode)
+	}
+
+	return nil
+}
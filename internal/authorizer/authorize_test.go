@@ -1,13 +1,26 @@
 package authorizer
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/go-kit/log"
+	"github.com/observatorium/opa-openshift/internal/cache"
 	"github.com/observatorium/opa-openshift/internal/config"
+	"github.com/observatorium/opa-openshift/internal/openshift/openshiftfakes"
 	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	authorizationv1 "k8s.io/api/authorization/v1"
 )
 
 var (
@@ -49,14 +62,18 @@ type fakeClient struct {
 	nsErr    error
 }
 
-func (f *fakeClient) AccessReview(user string, groups []string, verb, resource, resourceName, apiGroup, namespace string) (bool, error) {
+func (f *fakeClient) AccessReview(_ context.Context, user string, groups []string, verb, resource, resourceName, apiGroup, namespace string) (bool, error) {
 	if f.ssar {
 		return f.ssarFunc(verb, resource, resourceName, apiGroup, namespace)
 	}
 	return f.sarFunc(user, groups, verb, resource, resourceName, apiGroup, namespace)
 }
 
-func (f *fakeClient) ListNamespaces() ([]string, error) {
+func (f *fakeClient) RulesReview(_ context.Context, _ string) ([]authorizationv1.ResourceRule, bool, error) {
+	return nil, true, nil
+}
+
+func (f *fakeClient) ListNamespaces(_ context.Context) ([]string, error) {
 	return f.nsList, f.nsErr
 }
 
@@ -65,16 +82,22 @@ type fakeCache struct {
 	getFound    bool
 	getErr      error
 	setErr      error
+	setTTL      time.Duration
 }
 
 func (f *fakeCache) Get(_ string) (types.DataResponseV1, bool, error) {
 	return f.getResponse, f.getFound, f.getErr
 }
 
-func (f *fakeCache) Set(_ string, _ types.DataResponseV1) error {
+func (f *fakeCache) Set(_ string, _ types.DataResponseV1, ttl time.Duration) error {
+	f.setTTL = ttl
 	return f.setErr
 }
 
+func (f *fakeCache) Delete(_ ...string) error {
+	return nil
+}
+
 func TestAuthorize(t *testing.T) {
 	namespaceMatcher := &config.Matcher{
 		Keys:      []string{"kubernetes_namespace_name"},
@@ -315,6 +338,7 @@ func TestAuthorize(t *testing.T) {
 
 			a := New(c, l, cc, tc.matcher)
 			authorize, err := a.Authorize(
+				context.Background(),
 				"test-token", "test-user", []string{"test-group-1"},
 				tc.verb,
 				"application", "logs", "loki.grafana.com",
@@ -330,3 +354,599 @@ func TestAuthorize(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthorize_NamespacedSARConcurrency(t *testing.T) {
+	namespaceMatcher := &config.Matcher{
+		Keys:      []string{"kubernetes_namespace_name"},
+		MatcherOp: config.MatcherOr,
+	}
+
+	namespaces := make([]string, 40)
+	for i := range namespaces {
+		namespaces[i] = fmt.Sprintf("test-namespace-%02d", len(namespaces)-i)
+	}
+
+	tt := []struct {
+		desc        string
+		concurrency int
+	}{
+		{desc: "sequential", concurrency: 1},
+		{desc: "bounded", concurrency: 4},
+		{desc: "unbounded by namespaces", concurrency: 100},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var inFlight, maxInFlight atomic.Int32
+
+			c := &openshiftfakes.FakeClient{}
+			c.AccessReviewCalls(func(_ context.Context, _ string, _ []string, _, _, _, _, namespace string) (bool, error) {
+				if namespace == "" {
+					return false, nil
+				}
+
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+
+				for {
+					m := maxInFlight.Load()
+					if n <= m || maxInFlight.CompareAndSwap(m, n) {
+						break
+					}
+				}
+
+				time.Sleep(5 * time.Millisecond)
+
+				return true, nil
+			})
+
+			a := New(c, log.NewNopLogger(), &fakeCache{}, namespaceMatcher, WithSARConcurrency(tc.concurrency))
+			res, err := a.Authorize(
+				context.Background(),
+				"test-token", "test-user", []string{"test-group-1"},
+				GetVerb,
+				"application", "logs", "loki.grafana.com",
+				namespaces, false,
+			)
+			require.NoError(t, err)
+			require.Equal(t, len(namespaces)+1, c.AccessReviewCallCount())
+			require.LessOrEqual(t, maxInFlight.Load(), int32(tc.concurrency))
+			if tc.concurrency > 1 {
+				require.Greater(t, maxInFlight.Load(), int32(1))
+			}
+
+			sorted := slices.Clone(namespaces)
+			slices.Sort(sorted)
+
+			want, err := newDataResponseV1(sorted, namespaceMatcher, MatcherOptions{})
+			require.NoError(t, err)
+			require.Equal(t, want, res)
+		})
+	}
+}
+
+func TestAuthorize_NamespacedSARCancelsOnError(t *testing.T) {
+	c := &openshiftfakes.FakeClient{}
+	c.AccessReviewCalls(func(_ context.Context, _ string, _ []string, _, _, _, _, namespace string) (bool, error) {
+		if namespace == "" {
+			return false, nil
+		}
+
+		return false, errNamespacedSAR
+	})
+
+	matcher := &config.Matcher{Keys: []string{"kubernetes_namespace_name"}}
+	a := New(c, log.NewNopLogger(), &fakeCache{}, matcher, WithSARConcurrency(1))
+	_, err := a.Authorize(
+		context.Background(),
+		"test-token", "test-user", []string{"test-group-1"},
+		GetVerb,
+		"application", "logs", "loki.grafana.com",
+		[]string{"test-namespace-0", "test-namespace-1", "test-namespace-2"}, false,
+	)
+	require.EqualError(t, err, "namespaced SAR failed: namespaced SAR error")
+	// One cluster-wide review and the first namespaced one, the remaining are skipped.
+	require.Equal(t, 2, c.AccessReviewCallCount())
+}
+
+func TestAuthorize_CacheTTLs(t *testing.T) {
+	ttls := cache.TTLs{Allowed: 5 * time.Minute, Partial: time.Minute, Denied: 10 * time.Second}
+	matcher := &config.Matcher{Keys: []string{"kubernetes_namespace_name"}}
+
+	tt := []struct {
+		desc       string
+		matcher    *config.Matcher
+		clusterSAR bool
+		nsSAR      bool
+		wantTTL    time.Duration
+	}{
+		{
+			desc:       "allowed",
+			matcher:    config.EmptyMatcher(),
+			clusterSAR: true,
+			wantTTL:    5 * time.Minute,
+		},
+		{
+			desc:    "partially allowed",
+			matcher: matcher,
+			nsSAR:   true,
+			wantTTL: time.Minute,
+		},
+		{
+			desc:    "denied",
+			matcher: matcher,
+			wantTTL: 10 * time.Second,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			c := &openshiftfakes.FakeClient{}
+			c.AccessReviewCalls(func(_ context.Context, _ string, _ []string, _, _, _, _, namespace string) (bool, error) {
+				if namespace == "" {
+					return tc.clusterSAR, nil
+				}
+
+				return tc.nsSAR, nil
+			})
+
+			cc := &fakeCache{}
+			a := New(c, log.NewNopLogger(), cc, tc.matcher, WithCacheTTLs(ttls))
+			_, err := a.Authorize(
+				context.Background(),
+				"test-token", "test-user", []string{"test-group-1"},
+				GetVerb,
+				"application", "logs", "loki.grafana.com",
+				[]string{"test-namespace-1"}, false,
+			)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantTTL, cc.setTTL)
+		})
+	}
+}
+
+func TestAuthorize_NamespaceCache(t *testing.T) {
+	matcher := &config.Matcher{Keys: []string{"kubernetes_namespace_name"}}
+
+	var reviewed []string
+	c := &openshiftfakes.FakeClient{}
+	c.AccessReviewCalls(func(_ context.Context, _ string, _ []string, _, _, _, _, namespace string) (bool, error) {
+		reviewed = append(reviewed, namespace)
+		return namespace == "ns-a" || namespace == "ns-c", nil
+	})
+
+	cc := cache.NewInMemoryCache(60, 0, 0)
+	authorize := func(namespaces ...string) types.DataResponseV1 {
+		t.Helper()
+
+		a := New(c, log.NewNopLogger(), cc, matcher, WithSARConcurrency(1), WithNamespaceCache(true))
+		res, err := a.Authorize(
+			context.Background(),
+			"test-token", "test-user", []string{"test-group-1"},
+			GetVerb,
+			"application", "logs", "loki.grafana.com",
+			namespaces, false,
+		)
+		require.NoError(t, err)
+
+		return res
+	}
+
+	res := authorize("ns-a", "ns-b")
+	want, err := newDataResponseV1([]string{"ns-a"}, matcher, MatcherOptions{})
+	require.NoError(t, err)
+	require.Equal(t, want, res)
+	require.Equal(t, []string{"", "ns-a", "ns-b"}, reviewed)
+
+	// Only the namespace not yet known is reviewed, the other outcomes are reused.
+	reviewed = nil
+	res = authorize("ns-c", "ns-b", "ns-a")
+	want, err = newDataResponseV1([]string{"ns-a", "ns-c"}, matcher, MatcherOptions{})
+	require.NoError(t, err)
+	require.Equal(t, want, res)
+	require.Equal(t, []string{"ns-c"}, reviewed)
+}
+
+func TestAuthorize_Stats(t *testing.T) {
+	matcher := &config.Matcher{Keys: []string{"kubernetes_namespace_name"}}
+
+	c := &openshiftfakes.FakeClient{}
+	c.AccessReviewCalls(func(_ context.Context, _ string, _ []string, _, _, _, _, namespace string) (bool, error) {
+		return namespace == "ns-a" || namespace == "ns-c", nil
+	})
+
+	cc := cache.NewInMemoryCache(60, 0, 0)
+	authorize := func() Stats {
+		t.Helper()
+
+		a := New(c, log.NewNopLogger(), cc, matcher)
+		_, err := a.Authorize(
+			context.Background(),
+			"test-token", "test-user", []string{"test-group-1"},
+			GetVerb,
+			"application", "logs", "loki.grafana.com",
+			[]string{"ns-a", "ns-b", "ns-c"}, false,
+		)
+		require.NoError(t, err)
+
+		return a.Stats()
+	}
+
+	require.Equal(t, Stats{Reviews: 4, AllowedNamespaces: []string{"ns-a", "ns-c"}}, authorize())
+	require.Equal(t, Stats{CacheHit: true}, authorize())
+}
+
+func TestAuthorize_Tracing(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+
+	c := &openshiftfakes.FakeClient{}
+	c.AccessReviewReturns(true, nil)
+
+	a := New(c, log.NewNopLogger(), &fakeCache{}, config.EmptyMatcher(), WithTracerProvider(tp))
+	_, err := a.Authorize(
+		context.Background(),
+		"test-token", "test-user", []string{"test-group-1"},
+		GetVerb,
+		"application", "logs", "loki.grafana.com",
+		[]string{"test-namespace-1"}, false,
+	)
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, s := range exp.GetSpans() {
+		names = append(names, s.Name)
+	}
+
+	require.Equal(t, []string{"cache.get", "evaluate", "cache.set"}, names)
+}
+
+func TestAuthorize_RulesReview(t *testing.T) {
+	matcher := &config.Matcher{Keys: []string{"kubernetes_namespace_name"}}
+
+	c := &openshiftfakes.FakeClient{}
+	c.AccessReviewCalls(func(_ context.Context, _ string, _ []string, _, _, _, _, namespace string) (bool, error) {
+		return namespace == "test-namespace-2", nil
+	})
+	c.RulesReviewCalls(func(_ context.Context, namespace string) ([]authorizationv1.ResourceRule, bool, error) {
+		switch namespace {
+		case "test-namespace-0":
+			return []authorizationv1.ResourceRule{
+				{Verbs: []string{"get"}, APIGroups: []string{"loki.grafana.com"}, Resources: []string{"application"}, ResourceNames: []string{"logs"}},
+			}, false, nil
+		case "test-namespace-1":
+			return []authorizationv1.ResourceRule{
+				{Verbs: []string{"get"}, APIGroups: []string{"loki.grafana.com"}, Resources: []string{"infrastructure"}},
+			}, false, nil
+		default:
+			return nil, true, nil
+		}
+	})
+
+	a := New(c, log.NewNopLogger(), &fakeCache{}, matcher, WithRulesReview(true))
+	res, err := a.Authorize(
+		context.Background(),
+		"test-token", "test-user", []string{"test-group-1"},
+		GetVerb,
+		"application", "logs", "loki.grafana.com",
+		[]string{"test-namespace-0", "test-namespace-1", "test-namespace-2"}, false,
+	)
+	require.NoError(t, err)
+
+	want, err := newDataResponseV1([]string{"test-namespace-0", "test-namespace-2"}, matcher, MatcherOptions{})
+	require.NoError(t, err)
+	require.Equal(t, want, res)
+
+	require.Equal(t, 3, c.RulesReviewCallCount())
+	// One cluster-wide review and the fallback for the incomplete rules of test-namespace-2.
+	require.Equal(t, 2, c.AccessReviewCallCount())
+	_, _, _, _, _, _, _, ns := c.AccessReviewArgsForCall(1)
+	require.Equal(t, "test-namespace-2", ns)
+}
+
+func TestAuthorize_RulesReviewError(t *testing.T) {
+	c := &openshiftfakes.FakeClient{}
+	c.RulesReviewReturns(nil, false, errTestSAR)
+
+	matcher := &config.Matcher{Keys: []string{"kubernetes_namespace_name"}}
+	a := New(c, log.NewNopLogger(), &fakeCache{}, matcher, WithRulesReview(true))
+	_, err := a.Authorize(
+		context.Background(),
+		"test-token", "test-user", []string{"test-group-1"},
+		GetVerb,
+		"application", "logs", "loki.grafana.com",
+		[]string{"test-namespace-0"}, false,
+	)
+	require.EqualError(t, err, "namespaced SSRR failed: test SAR error")
+}
+
+func TestAuthorize_ContextDone(t *testing.T) {
+	blockingSAR := func(ctx context.Context, _ string, _ []string, _, _, _, _, namespace string) (bool, error) {
+		if namespace == "" {
+			return false, nil
+		}
+
+		<-ctx.Done()
+
+		return false, ctx.Err()
+	}
+
+	matcher := &config.Matcher{Keys: []string{"kubernetes_namespace_name"}}
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		t.Parallel()
+
+		c := &openshiftfakes.FakeClient{}
+		c.AccessReviewCalls(blockingSAR)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		a := New(c, log.NewNopLogger(), &fakeCache{}, matcher)
+		_, err := a.Authorize(
+			ctx,
+			"test-token", "test-user", []string{"test-group-1"},
+			GetVerb,
+			"application", "logs", "loki.grafana.com",
+			[]string{"test-namespace-0"}, false,
+		)
+
+		var sce *StatusCodeError
+		require.ErrorAs(t, err, &sce)
+		require.Equal(t, http.StatusGatewayTimeout, sce.StatusCode())
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		t.Parallel()
+
+		c := &openshiftfakes.FakeClient{}
+		c.AccessReviewCalls(blockingSAR)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		a := New(c, log.NewNopLogger(), &fakeCache{}, matcher)
+		_, err := a.Authorize(
+			ctx,
+			"test-token", "test-user", []string{"test-group-1"},
+			GetVerb,
+			"application", "logs", "loki.grafana.com",
+			[]string{"test-namespace-0"}, false,
+		)
+
+		var sce *StatusCodeError
+		require.ErrorAs(t, err, &sce)
+		require.Equal(t, http.StatusServiceUnavailable, sce.StatusCode())
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestAuthorize_DeduplicatesConcurrentDecisions(t *testing.T) {
+	const callers = 30
+
+	release := make(chan struct{})
+
+	c := &openshiftfakes.FakeClient{}
+	c.AccessReviewCalls(func(_ context.Context, _ string, _ []string, _, _, _, _, _ string) (bool, error) {
+		<-release
+
+		return true, nil
+	})
+
+	d := NewDeduplicator(nil)
+
+	var wg sync.WaitGroup
+	results := make([]types.DataResponseV1, callers)
+	errs := make([]error, callers)
+
+	for i := range callers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			a := New(c, log.NewNopLogger(), &fakeCache{}, config.EmptyMatcher(), WithDeduplicator(d))
+			results[i], errs[i] = a.Authorize(
+				context.Background(),
+				"test-token", "test-user", []string{"test-group-1"},
+				GetVerb,
+				"application", "logs", "loki.grafana.com",
+				[]string{"test-namespace-0"}, false,
+			)
+		}()
+	}
+
+	// Give all callers the chance to join the in-flight decision.
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i := range callers {
+		require.NoError(t, errs[i])
+		require.Equal(t, minimalDataResponseV1(true), results[i])
+	}
+
+	require.Equal(t, 1, c.AccessReviewCallCount())
+	require.InDelta(t, callers-1, testutil.ToFloat64(d.deduplicated), 0)
+}
+
+func TestAuthorize_StaleWhileRevalidate(t *testing.T) {
+	var allowed atomic.Bool
+
+	allowed.Store(true)
+
+	c := &openshiftfakes.FakeClient{}
+	c.AccessReviewCalls(func(_ context.Context, _ string, _ []string, _, _, _, _, _ string) (bool, error) {
+		return allowed.Load(), nil
+	})
+
+	var elapsed atomic.Int64
+
+	rv := NewRevalidator(nil, log.NewNopLogger(), time.Minute, time.Minute, time.Second)
+	rv.now = func() time.Time { return time.Now().Add(time.Duration(elapsed.Load())) }
+
+	cc := cache.NewInMemoryCache(60, 0, 0)
+	authorize := func() (types.DataResponseV1, Stats) {
+		t.Helper()
+
+		a := New(c, log.NewNopLogger(), cc, config.EmptyMatcher(),
+			WithCacheTTLs(cache.TTLs{Allowed: 10 * time.Second}), WithRevalidator(rv),
+		)
+		res, err := a.Authorize(
+			context.Background(),
+			"test-token", "test-user", []string{"test-group-1"},
+			GetVerb,
+			"application", "logs", "loki.grafana.com",
+			[]string{"test-namespace-0"}, false,
+		)
+		require.NoError(t, err)
+
+		return res, a.Stats()
+	}
+
+	res, stats := authorize()
+	require.Equal(t, minimalDataResponseV1(true), res)
+	require.False(t, stats.CacheHit)
+
+	res, stats = authorize()
+	require.Equal(t, minimalDataResponseV1(true), res)
+	require.Equal(t, Stats{CacheHit: true}, stats)
+
+	// Past the TTL, the stale decision is served while access is revoked in the background.
+	allowed.Store(false)
+	elapsed.Store(int64(15 * time.Second))
+
+	res, stats = authorize()
+	require.Equal(t, minimalDataResponseV1(true), res)
+	require.Equal(t, Stats{CacheHit: true, Stale: true}, stats)
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(rv.revalidations.WithLabelValues("success")) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	res, stats = authorize()
+	require.Equal(t, minimalDataResponseV1(false), res)
+	require.Equal(t, Stats{CacheHit: true}, stats)
+
+	require.InDelta(t, 1, testutil.ToFloat64(rv.stale), 0)
+}
+
+type fakeGenerations struct {
+	cluster    atomic.Int64
+	namespaces sync.Map
+}
+
+func (f *fakeGenerations) ClusterGeneration() string {
+	return fmt.Sprint(f.cluster.Load())
+}
+
+func (f *fakeGenerations) Generation(namespaces []string) string {
+	gen := f.ClusterGeneration()
+	for _, ns := range namespaces {
+		if v, ok := f.namespaces.Load(ns); ok {
+			gen += fmt.Sprintf(".%s=%d", ns, v)
+		}
+	}
+
+	return gen
+}
+
+func TestAuthorize_Generations(t *testing.T) {
+	c := &openshiftfakes.FakeClient{}
+	c.AccessReviewReturns(true, nil)
+
+	gens := &fakeGenerations{}
+	cc := cache.NewInMemoryCache(60, 0, 0)
+
+	authorize := func(namespaces ...string) Stats {
+		t.Helper()
+
+		a := New(c, log.NewNopLogger(), cc, config.EmptyMatcher(), WithGenerations(gens), WithNamespaceCache(true))
+		_, err := a.Authorize(
+			context.Background(),
+			"test-token", "test-user", []string{"test-group-1"},
+			GetVerb,
+			"application", "logs", "loki.grafana.com",
+			namespaces, false,
+		)
+		require.NoError(t, err)
+
+		return a.Stats()
+	}
+
+	require.False(t, authorize("ns-a").CacheHit)
+	require.False(t, authorize("ns-b").CacheHit)
+	require.True(t, authorize("ns-a").CacheHit)
+
+	// A change in a namespace only invalidates the decisions covering it.
+	gens.namespaces.Store("ns-a", 1)
+
+	require.False(t, authorize("ns-a").CacheHit)
+	require.True(t, authorize("ns-b").CacheHit)
+
+	// A change to cluster-scoped objects invalidates all decisions.
+	gens.cluster.Add(1)
+
+	require.Equal(t, Stats{Reviews: 1, AllowedNamespaces: []string{"ns-b"}}, authorize("ns-b"))
+}
+
+func TestAuthorize_EpochsAcrossReplicas(t *testing.T) {
+	c := &openshiftfakes.FakeClient{}
+	c.AccessReviewReturns(true, nil)
+
+	// Two replicas share a remote cache, but each keeps its own L1 tier and index.
+	shared := cache.NewInMemoryCache(60, 0, 0)
+	subjects := NewSubjectHasher([]byte("secret"))
+
+	newReplica := func() *cache.Indexed {
+		epochs := cache.NewEpochs(shared)
+		return cache.NewIndexed(cache.NewTiered(shared, time.Minute, 0), epochs, time.Minute, 0)
+	}
+
+	replicaA, replicaB := newReplica(), newReplica()
+
+	authorize := func(index *cache.Indexed, user string) Stats {
+		t.Helper()
+
+		a := New(c, log.NewNopLogger(), index, config.EmptyMatcher(),
+			WithSubjectHasher(subjects), WithEpochs(cache.NewEpochs(shared)), WithNamespaceCache(true),
+		)
+		_, err := a.Authorize(
+			context.Background(),
+			"test-token", user, []string{"test-group-1"},
+			GetVerb,
+			"application", "logs", "loki.grafana.com",
+			[]string{"ns-a"}, false,
+		)
+		require.NoError(t, err)
+
+		return a.Stats()
+	}
+
+	require.False(t, authorize(replicaB, "alice").CacheHit)
+	require.False(t, authorize(replicaB, "bob").CacheHit)
+	require.True(t, authorize(replicaB, "alice").CacheHit)
+
+	// A purge on replica A reaches the entries stored by replica B, including its L1 copies.
+	purged, err := replicaA.Purge(subjects.Tag("alice"))
+	require.NoError(t, err)
+	require.Equal(t, 0, purged)
+
+	require.Equal(t, Stats{Reviews: 1, AllowedNamespaces: []string{"ns-a"}}, authorize(replicaB, "alice"))
+	require.True(t, authorize(replicaB, "bob").CacheHit)
+
+	_, err = replicaA.Purge(TenantTag("application"))
+	require.NoError(t, err)
+
+	require.False(t, authorize(replicaB, "bob").CacheHit)
+
+	_, err = replicaA.PurgeAll()
+	require.NoError(t, err)
+
+	require.False(t, authorize(replicaB, "bob").CacheHit)
+}
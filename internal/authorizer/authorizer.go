@@ -1,11 +1,13 @@
 package authorizer
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"slices"
+	"sync/atomic"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -14,20 +16,80 @@ import (
 	"github.com/observatorium/opa-openshift/internal/openshift"
 	"github.com/open-policy-agent/opa/v1/server/types"
 	"github.com/prometheus/prometheus/model/labels"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
 	GetVerb    = "get"
 	CreateVerb = "create"
+
+	// DefaultSARConcurrency is the number of namespaced access reviews issued in parallel by default.
+	DefaultSARConcurrency = 10
+
+	tracerName = "github.com/observatorium/opa-openshift/internal/authorizer"
 )
 
 var errUnexpectedVerb = errors.New("unexpected verb")
 
 type Authorizer struct {
-	client  openshift.Client
-	logger  log.Logger
-	cache   cache.Cacher
-	matcher *config.Matcher
+	client         openshift.Client
+	logger         log.Logger
+	cache          cache.Cacher
+	matcher        *config.Matcher
+	sarConcurrency int
+	rulesReview    bool
+	dedup          *Deduplicator
+	ttls           cache.TTLs
+	namespaceCache bool
+	matcherOpts    MatcherOptions
+	subjects       *SubjectHasher
+	epochs         *cache.Epochs
+	tracer         trace.Tracer
+	revalidator    *Revalidator
+	generations    Generations
+
+	stats *statsRecorder
+}
+
+// Option configures optional behavior of an Authorizer.
+type Option func(*Authorizer)
+
+// WithSARConcurrency limits the number of namespaced access reviews issued in parallel.
+func WithSARConcurrency(n int) Option {
+	return func(a *Authorizer) {
+		if n > 0 {
+			a.sarConcurrency = n
+		}
+	}
+}
+
+// WithRulesReview resolves namespaced access by evaluating the rules returned by a
+// SelfSubjectRulesReview locally. Access reviews are only issued for namespaces
+// where the returned rules are incomplete. The rules are the ones of the holder of
+// the token of the client, so it must only be enabled with SelfSubjectAccessReviews.
+func WithRulesReview(enabled bool) Option {
+	return func(a *Authorizer) {
+		a.rulesReview = enabled
+	}
+}
+
+// WithCacheTTLs stores allowed, partially allowed and denied decisions in the
+// cache with their own time-to-live.
+func WithCacheTTLs(ttls cache.TTLs) Option {
+	return func(a *Authorizer) {
+		a.ttls = ttls
+	}
+}
+
+// WithTracerProvider records spans for the cache lookups and the evaluation of decisions.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(a *Authorizer) {
+		a.tracer = tp.Tracer(tracerName)
+	}
 }
 
 type AuthzResponseData struct {
@@ -48,11 +110,26 @@ func (s *StatusCodeError) StatusCode() int {
 	return s.SC
 }
 
-func New(c openshift.Client, l log.Logger, cc cache.Cacher, matcher *config.Matcher) *Authorizer {
-	return &Authorizer{client: c, logger: l, cache: cc, matcher: matcher}
+func (s *StatusCodeError) Unwrap() error {
+	return s.error
+}
+
+func New(c openshift.Client, l log.Logger, cc cache.Cacher, matcher *config.Matcher, opts ...Option) *Authorizer {
+	a := &Authorizer{
+		client: c, logger: l, cache: cc, matcher: matcher,
+		sarConcurrency: DefaultSARConcurrency,
+		tracer:         noop.NewTracerProvider().Tracer(tracerName),
+		stats:          &statsRecorder{},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
 }
 
 func (a *Authorizer) Authorize(
+	ctx context.Context,
 	token,
 	user string, groups []string,
 	verb, resource, resourceName, apiGroup string,
@@ -64,10 +141,23 @@ func (a *Authorizer) Authorize(
 		return types.DataResponseV1{}, &StatusCodeError{fmt.Errorf("%w: %s", errUnexpectedVerb, verb), http.StatusBadRequest}
 	}
 
-	cacheKey := generateCacheKey(token, user, groups, verb, resource, resourceName, apiGroup, namespaces, metadataOnly, a.matcher)
+	subject := newCacheSubject(token, user, groups, resource, a.subjects)
+
+	epoch, err := a.epochs.Epoch(subject.tags)
+	if err != nil {
+		return types.DataResponseV1{},
+			&StatusCodeError{fmt.Errorf("failed to fetch cache epoch: %w", err), http.StatusInternalServerError}
+	}
+
+	subject.epoch = epoch
+	cacheKey, tags := generateCacheKey(subject, verb, resource, resourceName, apiGroup, namespaces, metadataOnly, a.matcher, a.generation(namespaces))
 
 	level.Debug(a.logger).Log("msg", "looking up in cache", "cachekey", cacheKey) //nolint:errcheck
-	res, ok, err := a.cache.Get(cacheKey)
+	_, span := a.tracer.Start(ctx, "cache.get")
+	res, ok, err := a.cacheGet(cacheKey, tags)
+	span.SetAttributes(attribute.Bool("cache.hit", ok))
+	span.End()
+
 	if err != nil {
 		return types.DataResponseV1{},
 			&StatusCodeError{fmt.Errorf("failed to fetch authorization response from cache: %w", err), http.StatusInternalServerError}
@@ -75,27 +165,93 @@ func (a *Authorizer) Authorize(
 
 	if ok {
 		level.Debug(a.logger).Log("msg", "cache hit", "cachekey", cacheKey) //nolint:errcheck
+
+		var stale bool
+
+		res, stale = a.revalidator.load(res)
+		a.recordStats(func(s *Stats) { s.CacheHit, s.Stale = true, stale })
+
+		if stale {
+			bg := a.detached()
+			a.revalidator.revalidate(ctx, cacheKey, func(ctx context.Context) error {
+				_, err := bg.evaluate(ctx, cacheKey, tags, subject, user, groups, verb, resource, resourceName, apiGroup, namespaces, metadataOnly)
+				return err
+			})
+		}
+
 		return res, nil
 	}
 
-	res, err = a.authorizeInner(user, groups, verb, resource, resourceName, apiGroup, namespaces, metadataOnly)
+	var evaluated atomic.Bool
+
+	evaluate := func(ctx context.Context) (types.DataResponseV1, error) {
+		evaluated.Store(true)
+
+		return a.evaluate(ctx, cacheKey, tags, subject, user, groups, verb, resource, resourceName, apiGroup, namespaces, metadataOnly)
+	}
+
+	if a.dedup != nil {
+		res, err := a.dedup.do(ctx, cacheKey, evaluate)
+		a.recordStats(func(s *Stats) { s.Deduplicated = !evaluated.Load() })
+
+		return res, err
+	}
+
+	return evaluate(ctx)
+}
+
+// evaluate makes the decision against the API server and stores it in the cache.
+func (a *Authorizer) evaluate(
+	ctx context.Context, cacheKey string, tags []string, subject cacheSubject, user string, groups []string,
+	verb, resource, resourceName, apiGroup string, namespaces []string, metadataOnly bool,
+) (types.DataResponseV1, error) {
+	evalCtx, span := a.tracer.Start(ctx, "evaluate", trace.WithAttributes(attribute.Int("namespaces", len(namespaces))))
+	res, err := a.authorizeInner(evalCtx, subject, user, groups, verb, resource, resourceName, apiGroup, namespaces, metadataOnly)
+
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "evaluation failed")
+		span.End()
+
 		return types.DataResponseV1{}, err
 	}
 
-	if err := a.cache.Set(cacheKey, res); err != nil {
+	span.End()
+
+	_, span = a.tracer.Start(ctx, "cache.set")
+	entry, ttl := a.revalidator.store(res, a.ttls.For(res))
+	if err := a.cacheSet(cacheKey, entry, ttl, tags); err != nil {
 		// Only emit a warning when saving to cache fails, request still proceeds normally
 		level.Warn(a.logger).Log("msg", fmt.Sprintf("failed to save cached response: %s", err), "cachekey", cacheKey) //nolint:errcheck
 	}
+	span.End()
 
 	return res, nil
 }
 
-func (a *Authorizer) authorizeInner(user string, groups []string, verb, resource, resourceName, apiGroup string, namespaces []string, metadataOnly bool) (types.DataResponseV1, error) {
+// detached returns a copy of the Authorizer recording its own Stats, so that
+// work outliving the request does not alter the Stats of the request.
+func (a *Authorizer) detached() *Authorizer {
+	bg := *a
+	bg.stats = &statsRecorder{}
+
+	return &bg
+}
+
+func (a *Authorizer) authorizeInner(ctx context.Context, subject cacheSubject, user string, groups []string, verb, resource, resourceName, apiGroup string, namespaces []string, metadataOnly bool) (types.DataResponseV1, error) {
 	// check if user has cluster-wide access
-	clusterAllow, err := a.client.AccessReview(user, groups, verb, resource, resourceName, apiGroup, "")
+	clusterAllow, err := a.cachedAccess(subject, verb, resource, resourceName, apiGroup, "", func() (bool, error) {
+		a.recordReview()
+
+		allowed, err := a.client.AccessReview(ctx, user, groups, verb, resource, resourceName, apiGroup, "")
+		if err != nil {
+			return false, newAPIServerError(fmt.Errorf("cluster-wide SAR failed: %w", err))
+		}
+
+		return allowed, nil
+	})
 	if err != nil {
-		return types.DataResponseV1{}, &StatusCodeError{fmt.Errorf("cluster-wide SAR failed: %w", err), http.StatusUnauthorized}
+		return types.DataResponseV1{}, err
 	}
 
 	//nolint:errcheck
@@ -113,15 +269,15 @@ func (a *Authorizer) authorizeInner(user string, groups []string, verb, resource
 
 	if clusterAllow {
 		// user has cluster-wide access -> per-namespace check is not meaningful (always successful)
-		return a.authorizeClusterWide(namespaces)
+		return a.authorizeClusterWide(ctx, namespaces)
 	}
 
 	if metadataOnly && len(namespaces) == 0 {
 		// Only a metadata request and no namespaces provided -> populate with API list
 		var nsList []string
-		nsList, err = a.client.ListNamespaces()
+		nsList, err = a.client.ListNamespaces(ctx)
 		if err != nil {
-			return types.DataResponseV1{}, &StatusCodeError{fmt.Errorf("failed to access api server: %w", err), http.StatusUnauthorized}
+			return types.DataResponseV1{}, newAPIServerError(fmt.Errorf("failed to access api server: %w", err))
 		}
 		//nolint:errcheck
 		level.Debug(a.logger).Log("msg", "list namespaces for meta request",
@@ -136,34 +292,20 @@ func (a *Authorizer) authorizeInner(user string, groups []string, verb, resource
 		namespaces = nsList
 	}
 
-	allowed := []string{}
-	for _, ns := range namespaces {
-		var nsAllowed bool
-		nsAllowed, err = a.client.AccessReview(user, groups, verb, resource, resourceName, apiGroup, ns)
-		if err != nil {
-			return types.DataResponseV1{},
-				&StatusCodeError{fmt.Errorf("namespaced SAR failed: %w", err), http.StatusUnauthorized}
-		}
-		//nolint:errcheck
-		level.Debug(a.logger).Log(
-			"msg", "namespace-scoped AccessReview",
-			"user", user, "groups", fmt.Sprintf("%s", groups),
-			"res", resource, "name", resourceName, "api", apiGroup,
-			"allowed", nsAllowed, "namespace", ns,
-		)
-
-		if nsAllowed {
-			allowed = append(allowed, ns)
-		}
+	allowed, err := a.authorizeNamespaces(ctx, subject, user, groups, verb, resource, resourceName, apiGroup, namespaces)
+	if err != nil {
+		return types.DataResponseV1{}, err
 	}
 
+	a.recordAllowed(allowed)
+
 	if len(allowed) == 0 {
 		// all SARs were unsuccessful -> deny
 		return minimalDataResponseV1(false), nil
 	}
 
 	// allow access for the namespaces where the SAR was successful
-	res, err := newDataResponseV1(allowed, a.matcher)
+	res, err := newDataResponseV1(allowed, a.matcher, a.matcherOpts)
 	if err != nil {
 		return types.DataResponseV1{},
 			&StatusCodeError{fmt.Errorf("failed to create auth response: %w", err), http.StatusInternalServerError}
@@ -172,21 +314,107 @@ func (a *Authorizer) authorizeInner(user string, groups []string, verb, resource
 	return res, nil
 }
 
-func (a *Authorizer) authorizeClusterWide(namespaces []string) (types.DataResponseV1, error) {
+// authorizeNamespaces issues the namespaced access reviews in parallel, bounded by
+// the configured concurrency, and returns the allowed namespaces in sorted order.
+// The first failing review cancels all reviews not yet issued.
+func (a *Authorizer) authorizeNamespaces(ctx context.Context, subject cacheSubject, user string, groups []string, verb, resource, resourceName, apiGroup string, namespaces []string) ([]string, error) {
+	results := make([]bool, len(namespaces))
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(a.sarConcurrency)
+
+	for i, ns := range namespaces {
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return newAPIServerError(fmt.Errorf("namespaced SAR skipped: %w", err))
+			}
+
+			nsAllowed, err := a.cachedAccess(subject, verb, resource, resourceName, apiGroup, ns, func() (bool, error) {
+				return a.namespaceAccess(ctx, user, groups, verb, resource, resourceName, apiGroup, ns)
+			})
+			if err != nil {
+				return err
+			}
+			//nolint:errcheck
+			level.Debug(a.logger).Log(
+				"msg", "namespace-scoped AccessReview",
+				"user", user, "groups", fmt.Sprintf("%s", groups),
+				"res", resource, "name", resourceName, "api", apiGroup,
+				"allowed", nsAllowed, "namespace", ns,
+			)
+
+			results[i] = nsAllowed
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	allowed := []string{}
+	for i, ns := range namespaces {
+		if results[i] {
+			allowed = append(allowed, ns)
+		}
+	}
+
+	slices.Sort(allowed)
+
+	return allowed, nil
+}
+
+func (a *Authorizer) namespaceAccess(ctx context.Context, user string, groups []string, verb, resource, resourceName, apiGroup, namespace string) (bool, error) {
+	if a.rulesReview {
+		a.recordReview()
+
+		rules, incomplete, err := a.client.RulesReview(ctx, namespace)
+		if err != nil {
+			return false, newAPIServerError(fmt.Errorf("namespaced SSRR failed: %w", err))
+		}
+
+		// Rules can only grant access, so a match is final even for an incomplete rule set.
+		if allowed := rulesAllow(rules, verb, resource, resourceName, apiGroup); allowed || !incomplete {
+			return allowed, nil
+		}
+
+		//nolint:errcheck
+		level.Debug(a.logger).Log(
+			"msg", "incomplete SelfSubjectRulesReview, falling back to AccessReview",
+			"user", user, "namespace", namespace,
+		)
+	}
+
+	a.recordReview()
+
+	allowed, err := a.client.AccessReview(ctx, user, groups, verb, resource, resourceName, apiGroup, namespace)
+	if err != nil {
+		return false, newAPIServerError(fmt.Errorf("namespaced SAR failed: %w", err))
+	}
+
+	return allowed, nil
+}
+
+func (a *Authorizer) authorizeClusterWide(ctx context.Context, namespaces []string) (types.DataResponseV1, error) {
 	if a.matcher.IsEmpty() {
 		// user has cluster-wide access and does not need matcher -> allow
+		a.recordAllowed(namespaces)
+
 		return minimalDataResponseV1(true), nil
 	}
 
 	// user has cluster-wide access but needs a matcher -> populate namespaces from API list
-	nsList, err := a.client.ListNamespaces()
+	nsList, err := a.client.ListNamespaces(ctx)
 	if err != nil {
-		return types.DataResponseV1{}, &StatusCodeError{fmt.Errorf("failed to access api server: %w", err), http.StatusUnauthorized}
+		return types.DataResponseV1{}, newAPIServerError(fmt.Errorf("failed to access api server: %w", err))
 	}
 
 	if len(namespaces) == 0 {
 		// request was cluster-scoped, return matcher with all accessible namespaces
-		return newDataResponseV1(nsList, a.matcher)
+		a.recordAllowed(nsList)
+
+		return newDataResponseV1(nsList, a.matcher, a.matcherOpts)
 	}
 
 	nsMap := map[string]bool{}
@@ -201,8 +429,10 @@ func (a *Authorizer) authorizeClusterWide(namespaces []string) (types.DataRespon
 		}
 	}
 
+	a.recordAllowed(filtered)
+
 	// cluster-scoped SAR was successful, so namespaced SARs will be successful as well -> return matcher
-	return newDataResponseV1(filtered, a.matcher)
+	return newDataResponseV1(filtered, a.matcher, a.matcherOpts)
 }
 
 func minimalDataResponseV1(allowed bool) types.DataResponseV1 {
@@ -210,16 +440,16 @@ func minimalDataResponseV1(allowed bool) types.DataResponseV1 {
 	return types.DataResponseV1{Result: &res}
 }
 
-func newDataResponseV1(ns []string, matcher *config.Matcher) (types.DataResponseV1, error) {
+func newDataResponseV1(ns []string, matcher *config.Matcher, opts MatcherOptions) (types.DataResponseV1, error) {
 	if matcher.IsEmpty() && len(ns) > 0 {
 		return minimalDataResponseV1(true), nil
 	}
 
 	matchers := []*labels.Matcher{}
 	for _, key := range matcher.Keys {
-		lm, err := labels.NewMatcher(labels.MatchRegexp, key, strings.Join(ns, "|"))
+		lm, err := newLabelMatcher(key, ns, opts)
 		if err != nil {
-			return types.DataResponseV1{}, fmt.Errorf("failed to create new matcher: %w", err)
+			return types.DataResponseV1{}, err
 		}
 		matchers = append(matchers, lm)
 	}
@@ -1,31 +1,70 @@
 package authorizer
 
 import (
+	"crypto/hmac"
 	"crypto/sha256"
 	"fmt"
 	"slices"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/observatorium/opa-openshift/internal/cache"
 	"github.com/observatorium/opa-openshift/internal/config"
+	"github.com/open-policy-agent/opa/v1/server/types"
 )
 
+// cacheSubject identifies the subject of cached decisions and access review
+// outcomes, along with the tags their cache keys are stored with and the epoch
+// of those tags.
+type cacheSubject struct {
+	userHash string
+	tags     []string
+	epoch    string
+}
+
+func newCacheSubject(token, user string, groups []string, tenant string, subjects *SubjectHasher) cacheSubject {
+	tags := []string{TenantTag(tenant)}
+	if subjects != nil {
+		tags = append([]string{subjects.Tag(user)}, tags...)
+	}
+
+	return cacheSubject{
+		userHash: hashUserinfo(token, user, groups, subjects),
+		tags:     tags,
+	}
+}
+
+// generateCacheKey returns the key of a decision and the tags to store it with.
 func generateCacheKey(
-	token, user string, groups []string,
+	subject cacheSubject,
 	verb, resource, resourceName, apiGroup string, namespaces []string,
-	metadataOnly bool, matcher *config.Matcher,
-) string {
-	userHash := hashUserinfo(token, user, groups)
+	metadataOnly bool, matcher *config.Matcher, generation string,
+) (string, []string) {
 	matcherHash := hashMatcher(matcher)
 
-	return strings.Join([]string{
+	// Sort a copy, so that the same set of namespaces always yields the same key.
+	namespaces = slices.Clone(namespaces)
+	sort.Strings(namespaces)
+
+	parts := []string{
 		verb, fmt.Sprintf("%v", metadataOnly),
 		apiGroup, resourceName, resource, strings.Join(namespaces, ":"),
-		userHash, matcherHash,
-	}, ",")
+		subject.userHash, matcherHash,
+	}
+
+	if generation != "" {
+		parts = append(parts, "g:"+generation)
+	}
+
+	if subject.epoch != "" {
+		parts = append(parts, "e:"+subject.epoch)
+	}
+
+	return strings.Join(parts, ","), subject.tags
 }
 
-func hashUserinfo(token, user string, groups []string) string {
+func hashUserinfo(token, user string, groups []string, subjects *SubjectHasher) string {
 	hash := sha256.New()
 	hash.Write([]byte(token))
 	hash.Write([]byte(user))
@@ -36,7 +75,56 @@ func hashUserinfo(token, user string, groups []string) string {
 	}
 
 	hashBytes := hash.Sum([]byte{})
-	return fmt.Sprintf("%s:%x", user, hashBytes)
+
+	if subject := subjects.hash(user); subject != "" {
+		return fmt.Sprintf("%s:%x", subject, hashBytes)
+	}
+
+	return fmt.Sprintf("%x", hashBytes)
+}
+
+// SubjectHasher digests user names with an HMAC keyed by a secret, so that the
+// cache keys of a subject share a common part for lookups by subject without
+// revealing the subject or linking it across deployments using other secrets.
+type SubjectHasher struct {
+	key []byte
+}
+
+// NewSubjectHasher returns a SubjectHasher keyed by the given secret.
+func NewSubjectHasher(secret []byte) *SubjectHasher {
+	return &SubjectHasher{key: secret}
+}
+
+// WithSubjectHasher prefixes the user hash of cache keys with a digest of the
+// subject. Without it, the cache keys of a subject cannot be told apart.
+func WithSubjectHasher(h *SubjectHasher) Option {
+	return func(a *Authorizer) {
+		a.subjects = h
+	}
+}
+
+// WithEpochs includes the epochs of the subject and the tenant in the cache keys,
+// so that purging their tags makes their decisions unreachable on all replicas.
+func WithEpochs(e *cache.Epochs) Option {
+	return func(a *Authorizer) {
+		a.epochs = e
+	}
+}
+
+// Tag returns the cache key tag of the decisions of the given user.
+func (h *SubjectHasher) Tag(user string) string {
+	return subjectTagPrefix + h.hash(user)
+}
+
+func (h *SubjectHasher) hash(user string) string {
+	if h == nil {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(user))
+
+	return fmt.Sprintf("%x", mac.Sum(nil)[:8])
 }
 
 func hashMatcher(matcher *config.Matcher) string {
@@ -44,7 +132,7 @@ func hashMatcher(matcher *config.Matcher) string {
 		return "m:empty"
 	}
 
-	// Include the Keys slice (which can be modified by ViaQToOTELMigration)
+	// Include the Keys slice (which can be modified by SelectAliases)
 	keysCopy := slices.Clone(matcher.Keys)
 	sort.Strings(keysCopy) // Sort to ensure consistent hash regardless of order
 
@@ -53,6 +141,60 @@ func hashMatcher(matcher *config.Matcher) string {
 		hash.Write([]byte(key))
 	}
 
+	// The operator only changes the response when several keys are combined, so
+	// it is left out for single keys to keep their cache keys stable.
+	if len(keysCopy) > 1 {
+		hash.Write([]byte(matcher.MatcherOp))
+	}
+
 	hashBytes := hash.Sum([]byte{})
 	return fmt.Sprintf("m:%x", hashBytes)
 }
+
+// generateNamespaceCacheKey returns the key of a single access review outcome and
+// the tags to store it with. It does not depend on the other namespaces of the
+// request nor on the matcher, so the outcome can be reused across requests for
+// overlapping namespace sets. The cluster-wide access review is keyed by an empty namespace.
+func generateNamespaceCacheKey(subject cacheSubject, verb, resource, resourceName, apiGroup, namespace, generation string) (string, []string) {
+	parts := []string{
+		"ns", verb, apiGroup, resourceName, resource, namespace, subject.userHash,
+	}
+
+	if generation != "" {
+		parts = append(parts, "g:"+generation)
+	}
+
+	if subject.epoch != "" {
+		parts = append(parts, "e:"+subject.epoch)
+	}
+
+	return strings.Join(parts, ","), subject.tags
+}
+
+const (
+	subjectTagPrefix = "subject:"
+	tenantTagPrefix  = "tenant:"
+)
+
+// TenantTag returns the cache key tag of the decisions of the given tenant.
+func TenantTag(tenant string) string {
+	return tenantTagPrefix + tenant
+}
+
+// cacheGet looks up an entry, passing its tags to caches indexing entries by tag.
+func (a *Authorizer) cacheGet(key string, tags []string) (types.DataResponseV1, bool, error) {
+	if tc, ok := a.cache.(cache.TaggedCacher); ok {
+		return tc.GetTagged(key, tags) //nolint:wrapcheck
+	}
+
+	return a.cache.Get(key) //nolint:wrapcheck
+}
+
+// cacheSet stores an entry, passing its tags to caches indexing entries by tag.
+func (a *Authorizer) cacheSet(key string, res types.DataResponseV1, ttl time.Duration, tags []string) error {
+	if tc, ok := a.cache.(cache.TaggedCacher); ok {
+		return tc.SetTagged(key, res, ttl, tags) //nolint:wrapcheck
+	}
+
+	return a.cache.Set(key, res, ttl) //nolint:wrapcheck
+}
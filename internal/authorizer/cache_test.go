@@ -1,9 +1,12 @@
 package authorizer
 
 import (
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/observatorium/opa-openshift/internal/config"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -22,6 +25,8 @@ func TestGenerateCacheKey(t *testing.T) {
 		MatcherOp: config.MatcherOr,
 	}
 
+	subjects := NewSubjectHasher([]byte("secret"))
+
 	tt := []struct {
 		desc         string
 		token        string
@@ -34,6 +39,7 @@ func TestGenerateCacheKey(t *testing.T) {
 		namespaces   []string
 		metadataOnly bool
 		matcher      *config.Matcher
+		generation   string
 		wantKey      string
 	}{
 		{
@@ -52,7 +58,7 @@ func TestGenerateCacheKey(t *testing.T) {
 				"log-test-0",
 			},
 			matcher: testMatcher,
-			wantKey: "get,false,loki.grafana.com,application,logs,log-test-0,kube:admin:82516c2c21f2cb869241ffee091dd6e07b6fa1f74595536802d72de88b4c2130,m:e87a64ecd681d9831b31f30f429773801d276cf23e4b112cce2f077a1a092060",
+			wantKey: "get,false,loki.grafana.com,application,logs,log-test-0,68ac31f3c9affeae:82516c2c21f2cb869241ffee091dd6e07b6fa1f74595536802d72de88b4c2130,m:e87a64ecd681d9831b31f30f429773801d276cf23e4b112cce2f077a1a092060",
 		},
 		{
 			desc:  "kubeadmin - new OTEL matcher",
@@ -70,7 +76,7 @@ func TestGenerateCacheKey(t *testing.T) {
 				"log-test-0",
 			},
 			matcher: newMatcher,
-			wantKey: "get,false,loki.grafana.com,application,logs,log-test-0,kube:admin:82516c2c21f2cb869241ffee091dd6e07b6fa1f74595536802d72de88b4c2130,m:63ef1e06752e96333e3ae17570b81df7b194ad421c2a8920708832815bf0a6b0",
+			wantKey: "get,false,loki.grafana.com,application,logs,log-test-0,68ac31f3c9affeae:82516c2c21f2cb869241ffee091dd6e07b6fa1f74595536802d72de88b4c2130,m:63ef1e06752e96333e3ae17570b81df7b194ad421c2a8920708832815bf0a6b0",
 		},
 		{
 			desc:  "kubeadmin - empty matcher",
@@ -88,7 +94,7 @@ func TestGenerateCacheKey(t *testing.T) {
 				"log-test-0",
 			},
 			matcher: config.EmptyMatcher(),
-			wantKey: "get,false,loki.grafana.com,application,logs,log-test-0,kube:admin:82516c2c21f2cb869241ffee091dd6e07b6fa1f74595536802d72de88b4c2130,m:empty",
+			wantKey: "get,false,loki.grafana.com,application,logs,log-test-0,68ac31f3c9affeae:82516c2c21f2cb869241ffee091dd6e07b6fa1f74595536802d72de88b4c2130,m:empty",
 		},
 		{
 			desc:  "kubeadmin - nil matcher",
@@ -106,7 +112,7 @@ func TestGenerateCacheKey(t *testing.T) {
 				"log-test-0",
 			},
 			matcher: nil,
-			wantKey: "get,false,loki.grafana.com,application,logs,log-test-0,kube:admin:82516c2c21f2cb869241ffee091dd6e07b6fa1f74595536802d72de88b4c2130,m:empty",
+			wantKey: "get,false,loki.grafana.com,application,logs,log-test-0,68ac31f3c9affeae:82516c2c21f2cb869241ffee091dd6e07b6fa1f74595536802d72de88b4c2130,m:empty",
 		},
 		{
 			desc:  "logcollector",
@@ -123,7 +129,7 @@ func TestGenerateCacheKey(t *testing.T) {
 			apiGroup:     "loki.grafana.com",
 			namespaces:   []string{},
 			matcher:      testMatcher,
-			wantKey:      "create,false,loki.grafana.com,infrastructure,logs,,system:serviceaccount:openshift-logging:logcollector:4209c35b9ede6e39245d0c141006cb523d44bf65f04fdf834e164de263842753,m:e87a64ecd681d9831b31f30f429773801d276cf23e4b112cce2f077a1a092060",
+			wantKey:      "create,false,loki.grafana.com,infrastructure,logs,,0aaa0f4e834deca1:4209c35b9ede6e39245d0c141006cb523d44bf65f04fdf834e164de263842753,m:e87a64ecd681d9831b31f30f429773801d276cf23e4b112cce2f077a1a092060",
 		},
 		{
 			desc:  "test user",
@@ -141,7 +147,7 @@ func TestGenerateCacheKey(t *testing.T) {
 				"log-test-0",
 			},
 			matcher: testMatcher,
-			wantKey: "get,false,loki.grafana.com,application,logs,log-test-0,testuser-0:0cda1618ea4d6358ea3fb7e5270b8a85695fd4114a72f994fe71dde69df8d54a,m:e87a64ecd681d9831b31f30f429773801d276cf23e4b112cce2f077a1a092060",
+			wantKey: "get,false,loki.grafana.com,application,logs,log-test-0,ac24c6c96a611933:0cda1618ea4d6358ea3fb7e5270b8a85695fd4114a72f994fe71dde69df8d54a,m:e87a64ecd681d9831b31f30f429773801d276cf23e4b112cce2f077a1a092060",
 		},
 		{
 			desc:  "test user - metadata request",
@@ -160,21 +166,108 @@ func TestGenerateCacheKey(t *testing.T) {
 			},
 			metadataOnly: true,
 			matcher:      testMatcher,
-			wantKey:      "get,true,loki.grafana.com,application,logs,log-test-0,testuser-0:0cda1618ea4d6358ea3fb7e5270b8a85695fd4114a72f994fe71dde69df8d54a,m:e87a64ecd681d9831b31f30f429773801d276cf23e4b112cce2f077a1a092060",
+			wantKey:      "get,true,loki.grafana.com,application,logs,log-test-0,ac24c6c96a611933:0cda1618ea4d6358ea3fb7e5270b8a85695fd4114a72f994fe71dde69df8d54a,m:e87a64ecd681d9831b31f30f429773801d276cf23e4b112cce2f077a1a092060",
+		},
+		{
+			desc:  "test user - RBAC generation",
+			token: "sha256~tokentokentokentokentokentokentokentokentok",
+			user:  "testuser-0",
+			groups: []string{
+				"system:authenticated:oauth",
+				"system:authenticated",
+			},
+			verb:         GetVerb,
+			resource:     "logs",
+			resourceName: "application",
+			apiGroup:     "loki.grafana.com",
+			namespaces: []string{
+				"log-test-0",
+			},
+			matcher:    testMatcher,
+			generation: "9f3c1a2b4d5e6f70.1a2b3c4d5e6f7081",
+			wantKey:    "get,false,loki.grafana.com,application,logs,log-test-0,ac24c6c96a611933:0cda1618ea4d6358ea3fb7e5270b8a85695fd4114a72f994fe71dde69df8d54a,m:e87a64ecd681d9831b31f30f429773801d276cf23e4b112cce2f077a1a092060,g:9f3c1a2b4d5e6f70.1a2b3c4d5e6f7081",
 		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.desc, func(t *testing.T) {
-			got := generateCacheKey(tc.token, tc.user, tc.groups, tc.verb, tc.resource, tc.resourceName, tc.apiGroup, tc.namespaces, tc.metadataOnly, tc.matcher)
+			got, tags := generateCacheKey(newCacheSubject(tc.token, tc.user, tc.groups, tc.resource, subjects), tc.verb, tc.resource, tc.resourceName, tc.apiGroup, tc.namespaces, tc.metadataOnly, tc.matcher, tc.generation)
 
 			if got != tc.wantKey {
 				t.Errorf("got cache key %q, want %q", got, tc.wantKey)
 			}
 
+			if want := []string{subjects.Tag(tc.user), TenantTag(tc.resource)}; !slices.Equal(tags, want) {
+				t.Errorf("got cache key tags %q, want %q", tags, want)
+			}
+
 			if len(got) > maxCacheKeyLength {
 				t.Errorf("cache key is longer than %v characters: %v", maxCacheKeyLength, len(got))
 			}
 		})
 	}
 }
+
+func TestGenerateCacheKey_NamespaceOrder(t *testing.T) {
+	key := func(namespaces ...string) string {
+		subject := newCacheSubject("token", "alice", []string{"system:authenticated"}, "application", nil)
+		key, _ := generateCacheKey(subject, GetVerb, "application", "logs", "loki.grafana.com", namespaces, false, nil, "")
+
+		return key
+	}
+
+	namespaces := []string{"ns-b", "ns-a"}
+
+	require.Equal(t, key("ns-a", "ns-b"), key(namespaces...))
+	require.Contains(t, key(namespaces...), ",ns-a:ns-b,")
+	require.Equal(t, []string{"ns-b", "ns-a"}, namespaces)
+	require.NotEqual(t, key("ns-a", "ns-b"), key("ns-a"))
+}
+
+func TestHashUserinfo(t *testing.T) {
+	groups := []string{"system:authenticated"}
+
+	// Without a SubjectHasher, the user hash does not reveal the subject.
+	require.NotContains(t, hashUserinfo("token", "alice", groups, nil), ":")
+
+	secret := NewSubjectHasher([]byte("secret"))
+	alice := hashUserinfo("token", "alice", groups, secret)
+
+	require.True(t, strings.HasPrefix(alice, strings.TrimPrefix(secret.Tag("alice"), subjectTagPrefix)+":"))
+	require.True(t, strings.HasPrefix(hashUserinfo("other-token", "alice", groups, secret), strings.TrimPrefix(secret.Tag("alice"), subjectTagPrefix)+":"))
+	require.NotEqual(t, secret.Tag("alice"), secret.Tag("bob"))
+
+	// The digest of a subject depends on the secret.
+	require.NotEqual(t, secret.Tag("alice"), NewSubjectHasher([]byte("other")).Tag("alice"))
+}
+
+func TestHashMatcher(t *testing.T) {
+	and := &config.Matcher{Keys: []string{"namespace", "k8s_namespace_name"}, MatcherOp: config.MatcherAnd}
+	or := &config.Matcher{Keys: []string{"k8s_namespace_name", "namespace"}, MatcherOp: config.MatcherOr}
+
+	require.NotEqual(t, hashMatcher(and), hashMatcher(or))
+	require.Equal(t, hashMatcher(and), hashMatcher(&config.Matcher{Keys: []string{"k8s_namespace_name", "namespace"}, MatcherOp: config.MatcherAnd}))
+
+	// The operator does not matter for a single key.
+	require.Equal(t,
+		hashMatcher(&config.Matcher{Keys: []string{"namespace"}, MatcherOp: config.MatcherAnd}),
+		hashMatcher(&config.Matcher{Keys: []string{"namespace"}}),
+	)
+}
+
+func TestCacheKeyTags(t *testing.T) {
+	subjects := NewSubjectHasher([]byte("secret"))
+	groups := []string{"system:authenticated"}
+
+	// A comma in the resource name must not shift the tags.
+	alice := newCacheSubject("token", "alice", groups, "application", subjects)
+	_, tags := generateCacheKey(alice, GetVerb, "application", "logs,audit", "loki.grafana.com", []string{"ns-a"}, false, nil, "1.2")
+	require.Equal(t, []string{subjects.Tag("alice"), TenantTag("application")}, tags)
+
+	_, tags = generateNamespaceCacheKey(alice, GetVerb, "application", "logs,audit", "loki.grafana.com", "ns-a", "")
+	require.Equal(t, []string{subjects.Tag("alice"), TenantTag("application")}, tags)
+
+	// Without a SubjectHasher, keys are only tagged with their tenant.
+	_, tags = generateCacheKey(newCacheSubject("token", "alice", groups, "application", nil), GetVerb, "application", "logs", "loki.grafana.com", nil, false, nil, "")
+	require.Equal(t, []string{TenantTag("application")}, tags)
+}
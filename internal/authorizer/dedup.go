@@ -0,0 +1,77 @@
+package authorizer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+)
+
+// Deduplicator coalesces concurrent authorization decisions sharing the same
+// cache key, so that only one of them reaches the API server while the others
+// wait for and share its result.
+type Deduplicator struct {
+	group        singleflight.Group
+	deduplicated prometheus.Counter
+}
+
+func NewDeduplicator(r prometheus.Registerer) *Deduplicator {
+	d := &Deduplicator{
+		deduplicated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "opa_openshift_decisions_deduplicated_total",
+			Help: "Counts the number of authorization decisions served by an identical decision already in flight.",
+		}),
+	}
+
+	if r != nil {
+		r.MustRegister(d.deduplicated)
+	}
+
+	return d
+}
+
+// WithDeduplicator shares in-flight decisions across Authorizers using the same Deduplicator.
+func WithDeduplicator(d *Deduplicator) Option {
+	return func(a *Authorizer) {
+		a.dedup = d
+	}
+}
+
+func (d *Deduplicator) do(
+	ctx context.Context, key string,
+	fn func(context.Context) (types.DataResponseV1, error),
+) (types.DataResponseV1, error) {
+	var leader bool
+
+	ch := d.group.DoChan(key, func() (interface{}, error) {
+		leader = true
+
+		// The evaluation is shared, so it must not fail the waiting callers
+		// when the first caller goes away. Its deadline is kept nonetheless.
+		sharedCtx := context.WithoutCancel(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			sharedCtx, cancel = context.WithDeadline(sharedCtx, deadline)
+			defer cancel()
+		}
+
+		return fn(sharedCtx)
+	})
+
+	select {
+	case <-ctx.Done():
+		return types.DataResponseV1{}, newAPIServerError(fmt.Errorf("waiting for authorization decision: %w", ctx.Err()))
+	case r := <-ch:
+		if !leader {
+			d.deduplicated.Inc()
+		}
+
+		if r.Err != nil {
+			return types.DataResponseV1{}, r.Err
+		}
+
+		return r.Val.(types.DataResponseV1), nil //nolint:forcetypeassert
+	}
+}
@@ -0,0 +1,47 @@
+package authorizer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// newAPIServerError wraps a failed API server call into a StatusCodeError
+// carrying the status code matching the cause of the failure. Only a rejected
+// token is reported as unauthorized, so that an API server outage is not
+// mistaken for missing permissions.
+func newAPIServerError(err error) *StatusCodeError {
+	return &StatusCodeError{err, apiServerStatusCode(err)}
+}
+
+//nolint:cyclop
+func apiServerStatusCode(err error) int {
+	var netErr net.Error
+
+	switch {
+	case apierrors.IsUnauthorized(err):
+		return http.StatusUnauthorized
+	case apierrors.IsForbidden(err):
+		return http.StatusForbidden
+	case apierrors.IsTooManyRequests(err):
+		return http.StatusTooManyRequests
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return http.StatusGatewayTimeout
+	case apierrors.IsServiceUnavailable(err):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
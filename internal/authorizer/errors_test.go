@@ -0,0 +1,101 @@
+package authorizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var errUnknown = errors.New("something unexpected")
+
+func TestNewAPIServerError(t *testing.T) {
+	sar := schema.GroupResource{Group: "authorization.k8s.io", Resource: "subjectaccessreviews"}
+
+	connRefused := &url.Error{
+		Op:  "Post",
+		URL: "https://api.example.com:6443",
+		Err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+	}
+
+	tt := []struct {
+		desc     string
+		err      error
+		wantCode int
+	}{
+		{
+			desc:     "unauthorized",
+			err:      apierrors.NewUnauthorized("token expired"),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			desc:     "forbidden",
+			err:      apierrors.NewForbidden(sar, "", errUnknown),
+			wantCode: http.StatusForbidden,
+		},
+		{
+			desc:     "too many requests",
+			err:      apierrors.NewTooManyRequests("slow down", 1),
+			wantCode: http.StatusTooManyRequests,
+		},
+		{
+			desc:     "timeout",
+			err:      apierrors.NewTimeoutError("request did not complete", 1),
+			wantCode: http.StatusGatewayTimeout,
+		},
+		{
+			desc:     "server timeout",
+			err:      apierrors.NewServerTimeout(sar, "create", 1),
+			wantCode: http.StatusGatewayTimeout,
+		},
+		{
+			desc:     "deadline exceeded",
+			err:      &url.Error{Op: "Post", URL: "https://api.example.com:6443", Err: context.DeadlineExceeded},
+			wantCode: http.StatusGatewayTimeout,
+		},
+		{
+			desc:     "service unavailable",
+			err:      apierrors.NewServiceUnavailable("etcd is down"),
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			desc:     "connection refused",
+			err:      connRefused,
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			desc:     "canceled",
+			err:      context.Canceled,
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			desc:     "internal error",
+			err:      apierrors.NewInternalError(errUnknown),
+			wantCode: http.StatusInternalServerError,
+		},
+		{
+			desc:     "unknown error",
+			err:      errUnknown,
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			err := newAPIServerError(fmt.Errorf("cluster-wide SAR failed: %w", tc.err))
+			require.Equal(t, tc.wantCode, err.StatusCode())
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package authorizer
+
+// Generations versions cached decisions by the state of the RBAC objects they
+// depend on, so that a change to those objects makes the decisions unreachable.
+type Generations interface {
+	// ClusterGeneration returns the version of the cluster-scoped RBAC objects.
+	ClusterGeneration() string
+	// Generation returns the version of the cluster-scoped RBAC objects and of the
+	// namespaced ones in the given namespaces, or in all namespaces when none are given.
+	Generation(namespaces []string) string
+}
+
+// WithGenerations includes the generations of the RBAC objects in the cache keys.
+func WithGenerations(g Generations) Option {
+	return func(a *Authorizer) {
+		a.generations = g
+	}
+}
+
+func (a *Authorizer) generation(namespaces []string) string {
+	if a.generations == nil {
+		return ""
+	}
+
+	return a.generations.Generation(namespaces)
+}
+
+// namespaceGeneration returns the generation of a single access review, where
+// the cluster-wide review is denoted by an empty namespace.
+func (a *Authorizer) namespaceGeneration(namespace string) string {
+	if a.generations == nil {
+		return ""
+	}
+
+	if namespace == "" {
+		return a.generations.ClusterGeneration()
+	}
+
+	return a.generations.Generation([]string{namespace})
+}
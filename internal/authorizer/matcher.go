@@ -0,0 +1,121 @@
+package authorizer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+var errMatcherTooLarge = errors.New("label matcher too large")
+
+// MatcherOptions controls how the label matchers restricting a response to the
+// allowed namespaces are built.
+type MatcherOptions struct {
+	// SingleEqual emits an equality matcher when only a single namespace is allowed.
+	SingleEqual bool
+	// FactorPrefixes factors common prefixes of the namespaces out of the regex.
+	FactorPrefixes bool
+	// MaxSize is the maximum length of a matcher value in bytes; 0 disables the limit.
+	MaxSize int
+}
+
+// WithMatcherOptions configures how the label matchers of a response are built.
+func WithMatcherOptions(o MatcherOptions) Option {
+	return func(a *Authorizer) {
+		a.matcherOpts = o
+	}
+}
+
+// newLabelMatcher returns a matcher for the given label key, matching exactly the given namespaces.
+func newLabelMatcher(key string, ns []string, opts MatcherOptions) (*labels.Matcher, error) {
+	matchType, value := labels.MatchRegexp, namespaceRegex(ns, opts.FactorPrefixes)
+	if opts.SingleEqual && len(ns) == 1 {
+		matchType, value = labels.MatchEqual, ns[0]
+	}
+
+	if opts.MaxSize > 0 && len(value) > opts.MaxSize {
+		return nil, fmt.Errorf("%w: matcher for %d namespaces has %d bytes, exceeding the maximum of %d bytes",
+			errMatcherTooLarge, len(ns), len(value), opts.MaxSize)
+	}
+
+	lm, err := labels.NewMatcher(matchType, key, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new matcher: %w", err)
+	}
+
+	return lm, nil
+}
+
+// namespaceRegex returns an alternation of the quoted namespaces.
+func namespaceRegex(ns []string, factorPrefixes bool) string {
+	if factorPrefixes {
+		root := &prefixNode{}
+		for _, n := range ns {
+			root.insert(n)
+		}
+
+		return root.regex()
+	}
+
+	quoted := make([]string, 0, len(ns))
+	for _, n := range ns {
+		quoted = append(quoted, regexp.QuoteMeta(n))
+	}
+
+	return strings.Join(quoted, "|")
+}
+
+// prefixNode is a node of a trie of namespace names.
+type prefixNode struct {
+	children map[rune]*prefixNode
+	end      bool
+}
+
+func (n *prefixNode) insert(s string) {
+	for _, r := range s {
+		if n.children == nil {
+			n.children = map[rune]*prefixNode{}
+		}
+
+		child, ok := n.children[r]
+		if !ok {
+			child = &prefixNode{}
+			n.children[r] = child
+		}
+
+		n = child
+	}
+
+	n.end = true
+}
+
+// regex returns a regex matching exactly the names below this node, e.g.
+// "ns-(?:a|b(?:c)?)" for the names "ns-a", "ns-b" and "ns-bc".
+func (n *prefixNode) regex() string {
+	keys := make([]rune, 0, len(n.children))
+	for r := range n.children {
+		keys = append(keys, r)
+	}
+
+	slices.Sort(keys)
+
+	alts := make([]string, 0, len(keys))
+	for _, r := range keys {
+		alts = append(alts, regexp.QuoteMeta(string(r))+n.children[r].regex())
+	}
+
+	switch {
+	case len(alts) == 0:
+		return ""
+	case len(alts) == 1 && !n.end:
+		return alts[0]
+	case n.end:
+		return "(?:" + strings.Join(alts, "|") + ")?"
+	default:
+		return "(?:" + strings.Join(alts, "|") + ")"
+	}
+}
@@ -0,0 +1,111 @@
+package authorizer
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLabelMatcher(t *testing.T) {
+	tt := []struct {
+		desc      string
+		ns        []string
+		opts      MatcherOptions
+		wantType  labels.MatchType
+		wantValue string
+		wantErr   error
+	}{
+		{
+			desc:      "regex",
+			ns:        []string{"ns-a", "ns-b"},
+			wantType:  labels.MatchRegexp,
+			wantValue: "ns-a|ns-b",
+		},
+		{
+			desc:      "quoted regex",
+			ns:        []string{"ns.a", "ns+b"},
+			wantType:  labels.MatchRegexp,
+			wantValue: `ns\.a|ns\+b`,
+		},
+		{
+			desc:      "no namespaces",
+			ns:        []string{},
+			opts:      MatcherOptions{SingleEqual: true, FactorPrefixes: true},
+			wantType:  labels.MatchRegexp,
+			wantValue: "",
+		},
+		{
+			desc:      "single namespace as regex",
+			ns:        []string{"ns-a"},
+			wantType:  labels.MatchRegexp,
+			wantValue: "ns-a",
+		},
+		{
+			desc:      "single namespace as equality",
+			ns:        []string{"ns.a"},
+			opts:      MatcherOptions{SingleEqual: true},
+			wantType:  labels.MatchEqual,
+			wantValue: "ns.a",
+		},
+		{
+			desc:      "factored prefixes",
+			ns:        []string{"ns-a", "ns-b", "ns-bc", "other"},
+			opts:      MatcherOptions{FactorPrefixes: true},
+			wantType:  labels.MatchRegexp,
+			wantValue: "(?:ns-(?:a|b(?:c)?)|other)",
+		},
+		{
+			desc:      "within size cap",
+			ns:        []string{"ns-a", "ns-b"},
+			opts:      MatcherOptions{MaxSize: 9},
+			wantType:  labels.MatchRegexp,
+			wantValue: "ns-a|ns-b",
+		},
+		{
+			desc:    "exceeding size cap",
+			ns:      []string{"ns-a", "ns-b"},
+			opts:    MatcherOptions{MaxSize: 8},
+			wantErr: errMatcherTooLarge,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			lm, err := newLabelMatcher("namespace", tc.ns, tc.opts)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, "namespace", lm.Name)
+			require.Equal(t, tc.wantType, lm.Type)
+			require.Equal(t, tc.wantValue, lm.Value)
+		})
+	}
+}
+
+func TestNamespaceRegex_FactorPrefixes(t *testing.T) {
+	ns := []string{}
+	for i := range 200 {
+		ns = append(ns, fmt.Sprintf("team-%d-logs", i), fmt.Sprintf("team-%d", i))
+	}
+
+	plain := namespaceRegex(ns, false)
+	factored := namespaceRegex(ns, true)
+	require.Less(t, len(factored), len(plain))
+
+	re := regexp.MustCompile("^(?:" + factored + ")$")
+	for _, n := range ns {
+		require.True(t, re.MatchString(n), n)
+	}
+
+	for _, n := range []string{"team-", "team-200", "team-1-log", "team-1-logs-x", "xteam-1"} {
+		require.False(t, re.MatchString(n), n)
+	}
+}
@@ -0,0 +1,53 @@
+package authorizer
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log/level"
+)
+
+// WithNamespaceCache stores the outcome of every single access review in the
+// cache, so that requests for overlapping namespace sets only issue reviews for
+// namespaces not yet known.
+func WithNamespaceCache(enabled bool) Option {
+	return func(a *Authorizer) {
+		a.namespaceCache = enabled
+	}
+}
+
+// cachedAccess returns the outcome of an access review from the namespace cache
+// tier and only calls review when the outcome is not yet known.
+func (a *Authorizer) cachedAccess(
+	subject cacheSubject, verb, resource, resourceName, apiGroup, namespace string,
+	review func() (bool, error),
+) (bool, error) {
+	if !a.namespaceCache {
+		return review()
+	}
+
+	key, tags := generateNamespaceCacheKey(subject, verb, resource, resourceName, apiGroup, namespace, a.namespaceGeneration(namespace))
+
+	res, ok, err := a.cacheGet(key, tags)
+	if err != nil {
+		// The namespace tier is an optimization only, treat failures as a miss
+		level.Warn(a.logger).Log("msg", fmt.Sprintf("failed to fetch cached review: %s", err), "cachekey", key) //nolint:errcheck
+	}
+
+	if ok && res.Result != nil {
+		if allowed, isBool := (*res.Result).(bool); isBool {
+			return allowed, nil
+		}
+	}
+
+	allowed, err := review()
+	if err != nil {
+		return false, err
+	}
+
+	res = minimalDataResponseV1(allowed)
+	if err := a.cacheSet(key, res, a.ttls.For(res), tags); err != nil {
+		level.Warn(a.logger).Log("msg", fmt.Sprintf("failed to save cached review: %s", err), "cachekey", key) //nolint:errcheck
+	}
+
+	return allowed, nil
+}
@@ -0,0 +1,140 @@
+package authorizer
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+)
+
+// revalidateAfterKey is the key of the soft expiry kept in the metadata of cached decisions.
+const revalidateAfterKey = "io.observatorium.opa-openshift/revalidate-after"
+
+// Revalidator serves cached decisions past their soft expiry while refreshing
+// them in the background. A decision is kept in the cache for its TTL plus the
+// stale window: within its TTL it is served as is, within the stale window it
+// is served while it is revalidated, and past both it is evaluated anew.
+type Revalidator struct {
+	window     time.Duration
+	defaultTTL time.Duration
+	timeout    time.Duration
+	logger     log.Logger
+	now        func() time.Time
+
+	group         singleflight.Group
+	stale         prometheus.Counter
+	revalidations *prometheus.CounterVec
+}
+
+// NewRevalidator returns a Revalidator serving decisions up to window past their TTL.
+// The defaultTTL applies to decisions cached with the default expiration of the
+// cache backend, and timeout bounds a single background revalidation.
+func NewRevalidator(r prometheus.Registerer, l log.Logger, window, defaultTTL, timeout time.Duration) *Revalidator {
+	rv := &Revalidator{
+		window:     window,
+		defaultTTL: defaultTTL,
+		timeout:    timeout,
+		logger:     l,
+		now:        time.Now,
+		stale: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "opa_openshift_decisions_stale_total",
+			Help: "Counts the number of cached authorization decisions served past their TTL while being revalidated.",
+		}),
+		revalidations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "opa_openshift_decisions_revalidations_total",
+			Help: "Counts the number of background revalidations of stale authorization decisions.",
+		}, []string{"result"}),
+	}
+
+	if r != nil {
+		r.MustRegister(rv.stale, rv.revalidations)
+	}
+
+	return rv
+}
+
+// WithRevalidator serves stale decisions while revalidating them with the given Revalidator.
+func WithRevalidator(rv *Revalidator) Option {
+	return func(a *Authorizer) {
+		a.revalidator = rv
+	}
+}
+
+// store returns the decision annotated with its soft expiry and the TTL to cache it with.
+func (rv *Revalidator) store(res types.DataResponseV1, ttl time.Duration) (types.DataResponseV1, time.Duration) {
+	if rv == nil {
+		return res, ttl
+	}
+
+	if ttl <= 0 {
+		ttl = rv.defaultTTL
+	}
+
+	res.Metadata = maps.Clone(res.Metadata)
+	if res.Metadata == nil {
+		res.Metadata = map[string]interface{}{}
+	}
+
+	res.Metadata[revalidateAfterKey] = rv.now().Add(ttl).Format(time.RFC3339Nano)
+
+	return res, ttl + rv.window
+}
+
+// load returns the cached decision without its soft expiry and whether it is stale.
+func (rv *Revalidator) load(res types.DataResponseV1) (types.DataResponseV1, bool) {
+	v, ok := res.Metadata[revalidateAfterKey]
+	if !ok {
+		return res, false
+	}
+
+	res.Metadata = maps.Clone(res.Metadata)
+	delete(res.Metadata, revalidateAfterKey)
+
+	if len(res.Metadata) == 0 {
+		res.Metadata = nil
+	}
+
+	if rv == nil {
+		return res, false
+	}
+
+	s, _ := v.(string)
+
+	revalidateAfter, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil || rv.now().Before(revalidateAfter) {
+		return res, false
+	}
+
+	rv.stale.Inc()
+
+	return res, true
+}
+
+// revalidate runs fn in the background unless a revalidation of the key is already
+// in flight. It outlives the request, but keeps the values of its context.
+func (rv *Revalidator) revalidate(ctx context.Context, key string, fn func(context.Context) error) {
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		_, _, _ = rv.group.Do(key, func() (interface{}, error) {
+			ctx, cancel := context.WithTimeout(ctx, rv.timeout)
+			defer cancel()
+
+			if err := fn(ctx); err != nil {
+				rv.revalidations.WithLabelValues("error").Inc()
+				level.Warn(rv.logger).Log("msg", "failed to revalidate cached decision", "err", err) //nolint:errcheck
+
+				return nil, nil
+			}
+
+			rv.revalidations.WithLabelValues("success").Inc()
+
+			return nil, nil
+		})
+	}()
+}
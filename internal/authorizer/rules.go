@@ -0,0 +1,32 @@
+package authorizer
+
+import (
+	"slices"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+)
+
+const ruleWildcard = "*"
+
+// rulesAllow reports whether any of the resource rules returned by a
+// SelfSubjectRulesReview grants the verb on the given resource.
+func rulesAllow(rules []authorizationv1.ResourceRule, verb, resource, resourceName, apiGroup string) bool {
+	for _, rule := range rules {
+		if !matchesRule(rule.Verbs, verb) ||
+			!matchesRule(rule.APIGroups, apiGroup) ||
+			!matchesRule(rule.Resources, resource) {
+			continue
+		}
+
+		// An empty list of resource names permits all names.
+		if len(rule.ResourceNames) == 0 || slices.Contains(rule.ResourceNames, resourceName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesRule(values []string, want string) bool {
+	return slices.Contains(values, ruleWildcard) || slices.Contains(values, want)
+}
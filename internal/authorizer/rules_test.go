@@ -0,0 +1,87 @@
+package authorizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+)
+
+func TestRulesAllow(t *testing.T) {
+	tt := []struct {
+		desc  string
+		rules []authorizationv1.ResourceRule
+		want  bool
+	}{
+		{
+			desc: "no rules",
+			want: false,
+		},
+		{
+			desc: "exact match",
+			rules: []authorizationv1.ResourceRule{
+				{Verbs: []string{"get"}, APIGroups: []string{"loki.grafana.com"}, Resources: []string{"application"}, ResourceNames: []string{"logs"}},
+			},
+			want: true,
+		},
+		{
+			desc: "match without resource names",
+			rules: []authorizationv1.ResourceRule{
+				{Verbs: []string{"get"}, APIGroups: []string{"loki.grafana.com"}, Resources: []string{"application"}},
+			},
+			want: true,
+		},
+		{
+			desc: "wildcards",
+			rules: []authorizationv1.ResourceRule{
+				{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}},
+			},
+			want: true,
+		},
+		{
+			desc: "other verb",
+			rules: []authorizationv1.ResourceRule{
+				{Verbs: []string{"create"}, APIGroups: []string{"loki.grafana.com"}, Resources: []string{"application"}},
+			},
+			want: false,
+		},
+		{
+			desc: "other api group",
+			rules: []authorizationv1.ResourceRule{
+				{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"application"}},
+			},
+			want: false,
+		},
+		{
+			desc: "other resource",
+			rules: []authorizationv1.ResourceRule{
+				{Verbs: []string{"get"}, APIGroups: []string{"loki.grafana.com"}, Resources: []string{"audit"}},
+			},
+			want: false,
+		},
+		{
+			desc: "other resource name",
+			rules: []authorizationv1.ResourceRule{
+				{Verbs: []string{"get"}, APIGroups: []string{"loki.grafana.com"}, Resources: []string{"application"}, ResourceNames: []string{"traces"}},
+			},
+			want: false,
+		},
+		{
+			desc: "second rule matches",
+			rules: []authorizationv1.ResourceRule{
+				{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}},
+				{Verbs: []string{"get", "list"}, APIGroups: []string{"loki.grafana.com"}, Resources: []string{"application", "infrastructure"}},
+			},
+			want: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got := rulesAllow(tc.rules, GetVerb, "application", "logs", "loki.grafana.com")
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package authorizer
+
+import (
+	"slices"
+	"sync"
+)
+
+// Stats describes how the decision of an Authorizer was made.
+type Stats struct {
+	// CacheHit reports whether the decision was served from the cache.
+	CacheHit bool
+	// Stale reports whether the cached decision was served past its soft expiry
+	// while being revalidated in the background.
+	Stale bool
+	// Deduplicated reports whether the decision was shared by an identical decision in flight.
+	Deduplicated bool
+	// Reviews is the number of access and rules reviews issued to the API server.
+	Reviews int
+	// AllowedNamespaces are the namespaces access was granted to. It is only known
+	// for decisions evaluated against the API server.
+	AllowedNamespaces []string
+}
+
+type statsRecorder struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+// Stats returns how the last decision of the Authorizer was made.
+func (a *Authorizer) Stats() Stats {
+	a.stats.mu.Lock()
+	defer a.stats.mu.Unlock()
+
+	s := a.stats.stats
+	s.AllowedNamespaces = slices.Clone(s.AllowedNamespaces)
+
+	return s
+}
+
+func (a *Authorizer) recordStats(fn func(*Stats)) {
+	a.stats.mu.Lock()
+	defer a.stats.mu.Unlock()
+
+	fn(&a.stats.stats)
+}
+
+func (a *Authorizer) recordReview() {
+	a.recordStats(func(s *Stats) { s.Reviews++ })
+}
+
+func (a *Authorizer) recordAllowed(namespaces []string) {
+	a.recordStats(func(s *Stats) { s.AllowedNamespaces = namespaces })
+}
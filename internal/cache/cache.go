@@ -3,15 +3,50 @@ package cache
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/open-policy-agent/opa/v1/server/types"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-// Cacher is able to get and set key value pairs.
+// Cacher is able to get, set and delete key value pairs.
+// A zero TTL passed to Set stores the entry with the default expiration of the backend.
+// Delete ignores keys that are not cached.
 type Cacher interface {
 	Get(string) (types.DataResponseV1, bool, error)
-	Set(string, types.DataResponseV1) error
+	Set(string, types.DataResponseV1, time.Duration) error
+	Delete(...string) error
+}
+
+// TaggedCacher is a Cacher storing entries along with tags, e.g. the subject and the
+// tenant of a decision, to look them up and purge them by. Tags are expected to have
+// the form "<kind>:<value>".
+type TaggedCacher interface {
+	Cacher
+	// GetTagged is Get, but tags the entry when served, e.g. if stored by another replica.
+	GetTagged(string, []string) (types.DataResponseV1, bool, error)
+	// SetTagged is Set, but tags the entry.
+	SetTagged(string, types.DataResponseV1, time.Duration, []string) error
+}
+
+// TTLCacher is a Cacher able to return the remaining TTL of an entry along with it.
+type TTLCacher interface {
+	Cacher
+	// GetWithTTL is Get, but also returns the remaining TTL of the entry, or zero if
+	// the entry does not expire.
+	GetWithTTL(string) (types.DataResponseV1, time.Duration, bool, error)
+}
+
+// getWithTTL gets an entry along with its remaining TTL if the Cacher is a TTLCacher.
+// The TTL is zero otherwise.
+func getWithTTL(c Cacher, k string) (types.DataResponseV1, time.Duration, bool, error) {
+	if tc, ok := c.(TTLCacher); ok {
+		return tc.GetWithTTL(k)
+	}
+
+	res, ok, err := c.Get(k)
+
+	return res, 0, ok, err
 }
 
 type CacherWithMetrics interface {
@@ -0,0 +1,279 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	encryptionKeySize = 32
+	keyIDSize         = 4
+)
+
+var (
+	errInvalidKey       = errors.New("invalid encryption key")
+	errNoKeys           = errors.New("no encryption keys found")
+	errMalformedEntry   = errors.New("malformed encrypted cache entry")
+	errUnexpectedResult = errors.New("unexpected encrypted cache entry type")
+	errUnknownKey       = errors.New("cache entry encrypted with an unknown key")
+)
+
+// Keyring holds the key encrypting new cache entries and the keys able to decrypt
+// existing ones. Keeping the previous keys for decryption allows rotating the key
+// without invalidating all cached entries at once.
+type Keyring struct {
+	encrypt keyringKey
+	decrypt map[[keyIDSize]byte]cipher.AEAD
+}
+
+type keyringKey struct {
+	id   [keyIDSize]byte
+	aead cipher.AEAD
+}
+
+// NewKeyring returns a Keyring encrypting with the first 32 byte AES-256 key
+// and decrypting with all of the given keys.
+func NewKeyring(keys ...[]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errNoKeys
+	}
+
+	kr := &Keyring{decrypt: make(map[[keyIDSize]byte]cipher.AEAD, len(keys))}
+
+	for i, key := range keys {
+		k, err := newKeyringKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i+1, err)
+		}
+
+		if i == 0 {
+			kr.encrypt = k
+		}
+
+		kr.decrypt[k.id] = k.aead
+	}
+
+	return kr, nil
+}
+
+// LoadKeyring reads a Keyring from a file holding one base64 encoded key per line.
+// The first key encrypts new entries, the following ones only decrypt existing
+// entries. Empty lines and lines starting with '#' are ignored.
+func LoadKeyring(path string) (*Keyring, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	var keys [][]byte
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %d is not base64 encoded", errInvalidKey, len(keys)+1)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return NewKeyring(keys...)
+}
+
+func newKeyringKey(key []byte) (keyringKey, error) {
+	if len(key) != encryptionKeySize {
+		return keyringKey{}, fmt.Errorf("%w: want %d bytes, got %d", errInvalidKey, encryptionKeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return keyringKey{}, fmt.Errorf("%w: %w", errInvalidKey, err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return keyringKey{}, fmt.Errorf("%w: %w", errInvalidKey, err)
+	}
+
+	var id [keyIDSize]byte
+
+	sum := sha256.Sum256(key)
+	copy(id[:], sum[:])
+
+	return keyringKey{id: id, aead: aead}, nil
+}
+
+const (
+	metricNameCacheUnreadable = metricsPrefix + "unreadable_entries_total"
+
+	unreadableReasonPlaintext  = "plaintext"
+	unreadableReasonMalformed  = "malformed"
+	unreadableReasonUnknownKey = "unknown_key"
+	unreadableReasonDecrypt    = "decrypt"
+)
+
+var descCacheUnreadable = prometheus.NewDesc(
+	metricNameCacheUnreadable,
+	"Counts the number of cache entries that could not be decrypted and were treated as cache misses.",
+	[]string{"reason"}, nil)
+
+type encrypted struct {
+	logger  log.Logger
+	next    CacherWithMetrics
+	keyring *Keyring
+
+	mu         sync.Mutex
+	unreadable map[string]uint64
+}
+
+// NewEncrypted creates a new Cacher encrypting the entries stored in the given
+// Cacher with AES-256-GCM. Every entry is bound to its cache key, so entries
+// cannot be swapped between keys. Entries that cannot be decrypted, e.g. plaintext
+// entries written before encryption was enabled or entries encrypted with a key no
+// longer in the keyring, are treated as cache misses and replaced by the next decision.
+func NewEncrypted(l log.Logger, next CacherWithMetrics, keyring *Keyring) CacherWithMetrics {
+	return &encrypted{
+		logger:  l,
+		next:    next,
+		keyring: keyring,
+		unreadable: map[string]uint64{
+			unreadableReasonPlaintext:  0,
+			unreadableReasonMalformed:  0,
+			unreadableReasonUnknownKey: 0,
+			unreadableReasonDecrypt:    0,
+		},
+	}
+}
+
+func (e *encrypted) Describe(descs chan<- *prometheus.Desc) {
+	descs <- descCacheUnreadable
+	e.next.Describe(descs)
+}
+
+func (e *encrypted) Collect(metricsCh chan<- prometheus.Metric) {
+	e.mu.Lock()
+	for reason, n := range e.unreadable {
+		metricsCh <- prometheus.MustNewConstMetric(descCacheUnreadable, prometheus.CounterValue, float64(n), reason)
+	}
+	e.mu.Unlock()
+
+	e.next.Collect(metricsCh)
+}
+
+func (e *encrypted) Get(k string) (types.DataResponseV1, bool, error) {
+	res, _, ok, err := e.GetWithTTL(k)
+
+	return res, ok, err
+}
+
+func (e *encrypted) GetWithTTL(k string) (types.DataResponseV1, time.Duration, bool, error) {
+	sealed, ttl, ok, err := getWithTTL(e.next, k)
+	if err != nil || !ok {
+		return types.DataResponseV1{}, 0, false, err
+	}
+
+	res, reason, err := e.open(k, sealed)
+	if reason != "" {
+		e.mu.Lock()
+		e.unreadable[reason]++
+		e.mu.Unlock()
+
+		level.Debug(e.logger).Log("msg", "treating unreadable cache entry as a miss", "reason", reason, "err", err) //nolint:errcheck
+
+		return types.DataResponseV1{}, 0, false, nil
+	}
+
+	return res, ttl, true, nil
+}
+
+// open decrypts an entry. It returns the reason the entry is unreadable, if any.
+func (e *encrypted) open(k string, sealed types.DataResponseV1) (types.DataResponseV1, string, error) {
+	if sealed.Result == nil {
+		return types.DataResponseV1{}, unreadableReasonPlaintext, errUnexpectedResult
+	}
+
+	s, isString := (*sealed.Result).(string)
+	if !isString {
+		return types.DataResponseV1{}, unreadableReasonPlaintext, errUnexpectedResult
+	}
+
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(b) < keyIDSize {
+		return types.DataResponseV1{}, unreadableReasonMalformed, errMalformedEntry
+	}
+
+	var id [keyIDSize]byte
+
+	copy(id[:], b)
+
+	aead, ok := e.keyring.decrypt[id]
+	if !ok {
+		return types.DataResponseV1{}, unreadableReasonUnknownKey, errUnknownKey
+	}
+
+	b = b[keyIDSize:]
+	if len(b) < aead.NonceSize() {
+		return types.DataResponseV1{}, unreadableReasonMalformed, errMalformedEntry
+	}
+
+	v, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], []byte(k))
+	if err != nil {
+		return types.DataResponseV1{}, unreadableReasonDecrypt, fmt.Errorf("failed to decrypt cache entry: %w", err)
+	}
+
+	res, err := fromJSON(v)
+	if err != nil {
+		return types.DataResponseV1{}, unreadableReasonMalformed, err
+	}
+
+	return res, "", nil
+}
+
+func (e *encrypted) Set(k string, res types.DataResponseV1, ttl time.Duration) error {
+	v, err := toJSON(res)
+	if err != nil {
+		return err
+	}
+
+	key := e.keyring.encrypt
+
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	b := make([]byte, 0, keyIDSize+len(nonce)+len(v)+key.aead.Overhead())
+	b = append(b, key.id[:]...)
+	b = append(b, nonce...)
+	b = key.aead.Seal(b, nonce, v, []byte(k))
+
+	var sealed interface{} = base64.StdEncoding.EncodeToString(b)
+
+	return e.next.Set(k, types.DataResponseV1{Result: &sealed}, ttl)
+}
+
+func (e *encrypted) Delete(keys ...string) error {
+	return e.next.Delete(keys...)
+}
@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kit/log"
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, encryptionKeySize)
+}
+
+func TestEncrypted(t *testing.T) {
+	t.Parallel()
+
+	var partial interface{} = map[string]interface{}{"allowed": "true", "data": "namespace=~\"secret-ns\""}
+	res := types.DataResponseV1{Result: &partial}
+
+	oldKeys, err := NewKeyring(testKey(1))
+	require.NoError(t, err)
+
+	rotatedKeys, err := NewKeyring(testKey(2), testKey(1))
+	require.NoError(t, err)
+
+	otherKeys, err := NewKeyring(testKey(3))
+	require.NoError(t, err)
+
+	backend := NewInMemoryCache(60, 0, 0)
+
+	require.NoError(t, NewEncrypted(log.NewNopLogger(), backend, oldKeys).Set("key", res, 0))
+
+	stored, ok, err := backend.Get("key")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NotContains(t, (*stored.Result).(string), "secret-ns")
+
+	for _, tc := range []struct {
+		name    string
+		keyring *Keyring
+		key     string
+		wantOK  bool
+		wantErr bool
+	}{
+		{name: "same key", keyring: oldKeys, key: "key", wantOK: true},
+		{name: "rotated key", keyring: rotatedKeys, key: "key", wantOK: true},
+		{name: "unknown key", keyring: otherKeys, key: "key"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok, err := NewEncrypted(log.NewNopLogger(), backend, tc.keyring).Get(tc.key)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantOK, ok)
+
+			if tc.wantOK {
+				require.Equal(t, res, got)
+			}
+		})
+	}
+}
+
+func TestEncrypted_BoundToKey(t *testing.T) {
+	t.Parallel()
+
+	kr, err := NewKeyring(testKey(1))
+	require.NoError(t, err)
+
+	backend := NewInMemoryCache(60, 0, 0)
+	c := NewEncrypted(log.NewNopLogger(), backend, kr)
+
+	var allowed interface{} = true
+	require.NoError(t, c.Set("admin", types.DataResponseV1{Result: &allowed}, 0))
+
+	// Copying the entry of another key into place must not grant its result.
+	stored, _, err := backend.Get("admin")
+	require.NoError(t, err)
+	require.NoError(t, backend.Set("user", stored, 0))
+
+	_, ok, err := c.Get("user")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+type failingCache struct {
+	CacherWithMetrics
+}
+
+func (failingCache) Get(string) (types.DataResponseV1, bool, error) {
+	return types.DataResponseV1{}, false, errors.New("connection refused")
+}
+
+func TestEncrypted_TTL(t *testing.T) {
+	t.Parallel()
+
+	kr, err := NewKeyring(testKey(1))
+	require.NoError(t, err)
+
+	s := miniredis.RunT(t)
+	c := NewEncrypted(log.NewNopLogger(), NewRedis(RedisOptions{Addrs: []string{s.Addr()}}), kr)
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, c.Set("key", res, 5*time.Second))
+
+	got, ttl, ok, err := c.(TTLCacher).GetWithTTL("key")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, res, got)
+	require.Equal(t, 5*time.Second, ttl)
+}
+
+func TestEncrypted_Unreadable(t *testing.T) {
+	t.Parallel()
+
+	kr, err := NewKeyring(testKey(1))
+	require.NoError(t, err)
+
+	otherKeys, err := NewKeyring(testKey(2))
+	require.NoError(t, err)
+
+	backend := NewInMemoryCache(60, 0, 0)
+	c := NewEncrypted(log.NewNopLogger(), backend, kr)
+
+	var (
+		allowed   interface{} = true
+		partial   interface{} = map[string]interface{}{"allowed": "true", "data": "namespace=~\"ns\""}
+		malformed interface{} = "not-base64!"
+	)
+
+	// Plaintext entries written before encryption was enabled.
+	require.NoError(t, backend.Set("bool", types.DataResponseV1{Result: &allowed}, 0))
+	require.NoError(t, backend.Set("map", types.DataResponseV1{Result: &partial}, 0))
+	require.NoError(t, backend.Set("nil", types.DataResponseV1{}, 0))
+	require.NoError(t, backend.Set("malformed", types.DataResponseV1{Result: &malformed}, 0))
+	require.NoError(t, NewEncrypted(log.NewNopLogger(), backend, otherKeys).Set("rotated", types.DataResponseV1{Result: &allowed}, 0))
+
+	for _, k := range []string{"bool", "map", "nil", "malformed", "rotated"} {
+		_, ok, err := c.Get(k)
+		require.NoError(t, err, k)
+		require.False(t, ok, k)
+	}
+
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP opa_openshift_cache_unreadable_entries_total Counts the number of cache entries that could not be decrypted and were treated as cache misses.
+# TYPE opa_openshift_cache_unreadable_entries_total counter
+opa_openshift_cache_unreadable_entries_total{reason="decrypt"} 0
+opa_openshift_cache_unreadable_entries_total{reason="malformed"} 1
+opa_openshift_cache_unreadable_entries_total{reason="plaintext"} 3
+opa_openshift_cache_unreadable_entries_total{reason="unknown_key"} 1
+`), "opa_openshift_cache_unreadable_entries_total"))
+
+	// The next decision replaces the plaintext entry.
+	require.NoError(t, c.Set("bool", types.DataResponseV1{Result: &allowed}, 0))
+
+	got, ok, err := c.Get("bool")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, allowed, *got.Result)
+
+	// Errors of the backend are still reported.
+	_, _, err = NewEncrypted(log.NewNopLogger(), failingCache{backend}, kr).Get("bool")
+	require.Error(t, err)
+}
+
+func TestLoadKeyring(t *testing.T) {
+	t.Parallel()
+
+	encode := base64.StdEncoding.EncodeToString
+
+	for _, tc := range []struct {
+		name     string
+		content  string
+		wantKeys int
+		wantErr  bool
+	}{
+		{name: "single key", content: encode(testKey(1)), wantKeys: 1},
+		{name: "rotated keys", content: "# current\n" + encode(testKey(2)) + "\n\n# previous\n" + encode(testKey(1)) + "\n", wantKeys: 2},
+		{name: "empty", content: "# no keys\n", wantErr: true},
+		{name: "not base64", content: "not-base64!", wantErr: true},
+		{name: "short key", content: encode([]byte("short")), wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "keys")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			kr, err := LoadKeyring(path)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, kr.decrypt, tc.wantKeys)
+		})
+	}
+}
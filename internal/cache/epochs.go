@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/v1/server/types"
+)
+
+const (
+	epochKeyPrefix = "epoch,"
+	epochBytes     = 8
+)
+
+// Epochs keeps an epoch per tag in a Cacher shared by all replicas, e.g. a remote
+// cache. Keys of entries carry the epochs of their tags, so that bumping the epoch
+// of a tag makes its entries unreachable on every replica, including the copies
+// kept in an L1 tier. Epochs are random and never reused, so that an epoch expired
+// or evicted from the Cacher only makes the entries of the tag unreachable as well.
+type Epochs struct {
+	c Cacher
+}
+
+// NewEpochs returns Epochs kept in the given Cacher. It must be the backend shared
+// by all replicas without an L1 tier, so that every replica sees bumped epochs at once.
+func NewEpochs(c Cacher) *Epochs {
+	return &Epochs{c: c}
+}
+
+// Epoch returns the combined epoch of the given tags and of all entries.
+// Tags without an epoch yet are assigned one.
+func (e *Epochs) Epoch(tags []string) (string, error) {
+	if e == nil {
+		return "", nil
+	}
+
+	hash := sha256.New()
+
+	for _, tag := range append([]string{""}, tags...) {
+		epoch, err := e.get(tag)
+		if err != nil {
+			return "", err
+		}
+
+		hash.Write([]byte(epoch))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)[:epochBytes]), nil
+}
+
+// Bump assigns a new epoch to the given tag, making all entries with the tag unreachable.
+func (e *Epochs) Bump(tag string) error {
+	_, err := e.bump(tag)
+	return err
+}
+
+// BumpAll assigns a new epoch to all entries, making them unreachable.
+func (e *Epochs) BumpAll() error {
+	_, err := e.bump("")
+	return err
+}
+
+func (e *Epochs) get(tag string) (string, error) {
+	res, ok, err := e.c.Get(epochKeyPrefix + tag)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch epoch: %w", err)
+	}
+
+	if ok && res.Result != nil {
+		if epoch, isString := (*res.Result).(string); isString && epoch != "" {
+			return epoch, nil
+		}
+	}
+
+	return e.bump(tag)
+}
+
+func (e *Epochs) bump(tag string) (string, error) {
+	if e == nil {
+		return "", nil
+	}
+
+	b := make([]byte, epochBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate epoch: %w", err)
+	}
+
+	epoch := hex.EncodeToString(b)
+
+	var result interface{} = epoch
+	if err := e.c.Set(epochKeyPrefix+tag, types.DataResponseV1{Result: &result}, 0); err != nil {
+		return "", fmt.Errorf("failed to store epoch: %w", err)
+	}
+
+	return epoch, nil
+}
@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEpochs(t *testing.T) {
+	t.Parallel()
+
+	// Replicas share the backend keeping the epochs.
+	shared := NewInMemoryCache(60, 0, 0)
+	a, b := NewEpochs(shared), NewEpochs(shared)
+
+	alice := []string{"subject:alice", "tenant:logs"}
+	bob := []string{"subject:bob", "tenant:logs"}
+
+	epoch := func(e *Epochs, tags []string) string {
+		t.Helper()
+
+		epoch, err := e.Epoch(tags)
+		require.NoError(t, err)
+		require.NotEmpty(t, epoch)
+
+		return epoch
+	}
+
+	aliceEpoch, bobEpoch := epoch(a, alice), epoch(a, bob)
+	require.NotEqual(t, aliceEpoch, bobEpoch)
+	require.Equal(t, aliceEpoch, epoch(b, alice))
+
+	require.NoError(t, b.Bump("subject:alice"))
+	require.NotEqual(t, aliceEpoch, epoch(a, alice))
+	require.Equal(t, bobEpoch, epoch(a, bob))
+
+	require.NoError(t, b.Bump("tenant:logs"))
+	require.NotEqual(t, bobEpoch, epoch(a, bob))
+
+	bobEpoch = epoch(a, bob)
+	require.NoError(t, b.BumpAll())
+	require.NotEqual(t, bobEpoch, epoch(a, bob))
+
+	// A missing epoch is replaced by a new one instead of reverting to a former one.
+	aliceEpoch = epoch(a, alice)
+	require.NoError(t, shared.Delete(epochKeyPrefix+"subject:alice"))
+	require.NotEqual(t, aliceEpoch, epoch(a, alice))
+
+	got, err := (*Epochs)(nil).Epoch(alice)
+	require.NoError(t, err)
+	require.Empty(t, got)
+}
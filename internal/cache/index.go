@@ -0,0 +1,234 @@
+package cache
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Indexed is a TaggedCacher indexing the keys it stores or serves by their tags, so
+// that entries can be looked up by tag even if the backend cannot enumerate its keys.
+// The index only knows the keys passing through this instance: entries stored by
+// other replicas sharing a remote cache are not indexed until served here. Purges
+// therefore bump the epochs of the purged tags, which reach the entries of all replicas.
+// Entries stored or served without tags are indexed without tags.
+type Indexed struct {
+	next   CacherWithMetrics
+	epochs *Epochs
+
+	mu    sync.Mutex
+	keys  *ttlcache.Cache[string, []string]
+	byTag map[string]map[string]struct{}
+}
+
+// IndexStats summarizes the keys known to an Indexed cache.
+type IndexStats struct {
+	// Keys is the number of indexed keys.
+	Keys int `json:"keys"`
+	// Tags is the number of distinct tags of every kind, e.g. the number of subjects.
+	Tags map[string]int `json:"tags"`
+}
+
+// NewIndexed creates a new Indexed cache in front of the given Cacher, purging entries
+// with the given Epochs. Keys are dropped from the index after the TTL they were stored
+// with, or after defaultTTL when none was given. When the index holds capacity keys,
+// indexing another key drops the least recently indexed one; use 0 to disable the limit.
+func NewIndexed(next CacherWithMetrics, epochs *Epochs, defaultTTL time.Duration, capacity uint64) *Indexed {
+	i := &Indexed{
+		next:   next,
+		epochs: epochs,
+		keys: ttlcache.New(
+			ttlcache.WithTTL[string, []string](defaultTTL),
+			ttlcache.WithDisableTouchOnHit[string, []string](),
+			ttlcache.WithCapacity[string, []string](capacity),
+		),
+		byTag: map[string]map[string]struct{}{},
+	}
+	i.keys.OnEviction(i.evict)
+
+	go i.keys.Start()
+
+	return i
+}
+
+func (i *Indexed) Describe(descs chan<- *prometheus.Desc) {
+	i.next.Describe(descs)
+}
+
+func (i *Indexed) Collect(metricsCh chan<- prometheus.Metric) {
+	i.next.Collect(metricsCh)
+}
+
+func (i *Indexed) Get(k string) (types.DataResponseV1, bool, error) {
+	return i.GetTagged(k, nil)
+}
+
+func (i *Indexed) GetTagged(k string, tags []string) (types.DataResponseV1, bool, error) {
+	res, ok, err := i.next.Get(k)
+	if ok && !i.keys.Has(k) {
+		i.track(k, tags, ttlcache.DefaultTTL)
+	}
+
+	return res, ok, err
+}
+
+func (i *Indexed) Set(k string, res types.DataResponseV1, ttl time.Duration) error {
+	return i.SetTagged(k, res, ttl, nil)
+}
+
+func (i *Indexed) SetTagged(k string, res types.DataResponseV1, ttl time.Duration, tags []string) error {
+	if err := i.next.Set(k, res, ttl); err != nil {
+		return err
+	}
+
+	if ttl <= 0 {
+		ttl = ttlcache.DefaultTTL
+	}
+
+	i.track(k, tags, ttl)
+
+	return nil
+}
+
+func (i *Indexed) Delete(keys ...string) error {
+	if err := i.next.Delete(keys...); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, k := range keys {
+		if item := i.keys.Get(k); item != nil {
+			i.untag(k, item.Value())
+			i.keys.Delete(k)
+		}
+	}
+
+	return nil
+}
+
+// Keys returns the indexed keys carrying the given tag in lexical order.
+func (i *Indexed) Keys(tag string) []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	keys := make([]string, 0, len(i.byTag[tag]))
+	for k := range i.byTag[tag] {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// KeysWithPrefix returns the indexed keys starting with the given prefix in lexical order.
+func (i *Indexed) KeysWithPrefix(prefix string) []string {
+	var keys []string
+
+	for _, k := range i.keys.Keys() {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Purge makes the entries carrying the given tag unreachable on all replicas by bumping
+// the epoch of the tag, deletes the indexed ones and returns the number of deleted entries.
+func (i *Indexed) Purge(tag string) (int, error) {
+	if err := i.epochs.Bump(tag); err != nil {
+		return 0, err
+	}
+
+	keys := i.Keys(tag)
+	if err := i.Delete(keys...); err != nil {
+		return 0, err
+	}
+
+	return len(keys), nil
+}
+
+// PurgeAll makes all entries unreachable on all replicas by bumping the epoch of all
+// entries, deletes the indexed ones and returns the number of deleted entries. Other
+// data stored in a shared backend is left untouched.
+func (i *Indexed) PurgeAll() (int, error) {
+	if err := i.epochs.BumpAll(); err != nil {
+		return 0, err
+	}
+
+	keys := i.keys.Keys()
+	if err := i.Delete(keys...); err != nil {
+		return 0, err
+	}
+
+	return len(keys), nil
+}
+
+// Stats returns the number of indexed keys and of distinct tags of every kind.
+func (i *Indexed) Stats() IndexStats {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	stats := IndexStats{Keys: i.keys.Len(), Tags: map[string]int{}}
+
+	for tag := range i.byTag {
+		kind, _, _ := strings.Cut(tag, ":")
+		stats.Tags[kind]++
+	}
+
+	return stats
+}
+
+func (i *Indexed) track(k string, tags []string, ttl time.Duration) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// Drop the key from the tags it was indexed with before, in case they changed.
+	if item := i.keys.Get(k); item != nil {
+		i.untag(k, item.Value())
+	}
+
+	i.keys.Set(k, tags, ttl)
+
+	for _, tag := range tags {
+		if i.byTag[tag] == nil {
+			i.byTag[tag] = map[string]struct{}{}
+		}
+
+		i.byTag[tag][k] = struct{}{}
+	}
+}
+
+// evict drops a key expired or evicted from the index from its tags, unless the
+// key was indexed again in the meantime.
+func (i *Indexed) evict(_ context.Context, _ ttlcache.EvictionReason, item *ttlcache.Item[string, []string]) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.keys.Has(item.Key()) {
+		return
+	}
+
+	i.untag(item.Key(), item.Value())
+}
+
+func (i *Indexed) untag(k string, tags []string) {
+	for _, tag := range tags {
+		delete(i.byTag[tag], k)
+
+		if len(i.byTag[tag]) == 0 {
+			delete(i.byTag, tag)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/stretchr/testify/require"
+)
+
+// testTags returns the tags of keys of the form "<subject>/<tenant>/<name>".
+func testTags(key string) []string {
+	parts := strings.Split(key, "/")
+	return []string{"subject:" + parts[0], "tenant:" + parts[1]}
+}
+
+func TestIndexed(t *testing.T) {
+	t.Parallel()
+
+	next := NewInMemoryCache(60, 0, 0)
+	c := NewIndexed(next, NewEpochs(next), time.Minute, 0)
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	for _, k := range []string{"alice/logs/a", "alice/metrics/b", "bob/logs/c"} {
+		require.NoError(t, c.SetTagged(k, res, 0, testTags(k)))
+	}
+
+	// Entries stored by another replica are indexed once served.
+	require.NoError(t, next.Set("carol/logs/d", res, 0))
+
+	_, ok, err := c.GetTagged("carol/logs/d", testTags("carol/logs/d"))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.Equal(t, IndexStats{Keys: 4, Tags: map[string]int{"subject": 3, "tenant": 2}}, c.Stats())
+	require.Equal(t, []string{"alice/logs/a", "alice/metrics/b"}, c.Keys("subject:alice"))
+	require.Equal(t, []string{"alice/logs/a", "bob/logs/c", "carol/logs/d"}, c.Keys("tenant:logs"))
+	require.Equal(t, []string{"alice/metrics/b"}, c.KeysWithPrefix("alice/m"))
+
+	purged, err := c.Purge("subject:alice")
+	require.NoError(t, err)
+	require.Equal(t, 2, purged)
+	require.Empty(t, c.Keys("subject:alice"))
+	require.Equal(t, []string{"bob/logs/c", "carol/logs/d"}, c.Keys("tenant:logs"))
+
+	_, ok, err = next.Get("alice/logs/a")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Entries unknown to the index are not purged.
+	require.NoError(t, next.Set("dave/logs/e", res, 0))
+
+	purged, err = c.PurgeAll()
+	require.NoError(t, err)
+	require.Equal(t, 2, purged)
+	require.Equal(t, IndexStats{Keys: 0, Tags: map[string]int{}}, c.Stats())
+
+	_, ok, err = next.Get("bob/logs/c")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, ok, err = next.Get("dave/logs/e")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestIndexed_Expiration(t *testing.T) {
+	t.Parallel()
+
+	c := NewIndexed(NewInMemoryCache(60, 0, 0), nil, time.Minute, 0)
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, c.SetTagged("alice/logs/a", res, 10*time.Millisecond, testTags("alice/logs/a")))
+	require.NoError(t, c.SetTagged("alice/logs/b", res, 0, testTags("alice/logs/b")))
+
+	require.Eventually(t, func() bool {
+		return len(c.Keys("subject:alice")) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"alice/logs/b"}, c.Keys("tenant:logs"))
+}
@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"context"
+	"sync/atomic"
 	"time"
 
 	"github.com/jellydator/ttlcache/v3"
@@ -10,16 +12,45 @@ import (
 
 type inmemory struct {
 	tc *ttlcache.Cache[string, []byte]
+
+	expired, capacity atomic.Uint64
 }
 
-func NewInMemoryCache(expire int32) CacherWithMetrics {
+// NewInMemoryCache creates a new Cacher keeping entries in memory for the given
+// number of seconds. When the cache holds maxEntries entries or maxBytes bytes of
+// keys and values, inserting another entry evicts the least recently used ones;
+// use 0 to disable either limit. Expired entries are evicted in the background.
+func NewInMemoryCache(expire int32, maxEntries, maxBytes uint64) CacherWithMetrics {
 	expireDuration := time.Duration(int64(expire) * int64(time.Second))
-	tc := ttlcache.New(
+	opts := []ttlcache.Option[string, []byte]{
 		ttlcache.WithTTL[string, []byte](expireDuration),
 		ttlcache.WithDisableTouchOnHit[string, []byte](),
-	)
+		ttlcache.WithCapacity[string, []byte](maxEntries),
+	}
+
+	if maxBytes > 0 {
+		opts = append(opts, ttlcache.WithMaxCost[string, []byte](maxBytes, entrySize))
+	}
+
+	i := &inmemory{tc: ttlcache.New(opts...)}
+	i.tc.OnEviction(i.countEviction)
+
+	go i.tc.Start()
+
+	return i
+}
+
+func entrySize(item ttlcache.CostItem[string, []byte]) uint64 {
+	return uint64(len(item.Key) + len(item.Value))
+}
 
-	return &inmemory{tc: tc}
+func (i *inmemory) countEviction(_ context.Context, reason ttlcache.EvictionReason, _ *ttlcache.Item[string, []byte]) {
+	switch reason {
+	case ttlcache.EvictionReasonExpired:
+		i.expired.Add(1)
+	case ttlcache.EvictionReasonCapacityReached, ttlcache.EvictionReasonMaxCostExceeded:
+		i.capacity.Add(1)
+	}
 }
 
 func (i *inmemory) Describe(descs chan<- *prometheus.Desc) {
@@ -36,7 +67,10 @@ func (i *inmemory) Collect(metricsCh chan<- prometheus.Metric) {
 		prometheus.CounterValue, float64(metrics.Hits), metricsRequestResultHit)
 	metricsCh <- prometheus.MustNewConstMetric(descCacheRequests,
 		prometheus.CounterValue, float64(metrics.Misses), metricsRequestResultMiss)
-	metricsCh <- prometheus.MustNewConstMetric(descCacheEvictions, prometheus.CounterValue, float64(metrics.Evictions))
+	metricsCh <- prometheus.MustNewConstMetric(descCacheEvictions,
+		prometheus.CounterValue, float64(i.expired.Load()), metricsEvictionReasonExpired)
+	metricsCh <- prometheus.MustNewConstMetric(descCacheEvictions,
+		prometheus.CounterValue, float64(i.capacity.Load()), metricsEvictionReasonCapacity)
 }
 
 func (i *inmemory) Get(k string) (types.DataResponseV1, bool, error) {
@@ -54,13 +88,25 @@ func (i *inmemory) Get(k string) (types.DataResponseV1, bool, error) {
 	return res, true, nil
 }
 
-func (i *inmemory) Set(k string, res types.DataResponseV1) error {
+func (i *inmemory) Set(k string, res types.DataResponseV1, ttl time.Duration) error {
 	v, err := toJSON(res)
 	if err != nil {
 		return err
 	}
 
-	// Save entry to cache using globally-defined TTL
-	i.tc.Set(k, v, ttlcache.DefaultTTL)
+	if ttl <= 0 {
+		// Save entry to cache using globally-defined TTL
+		ttl = ttlcache.DefaultTTL
+	}
+
+	i.tc.Set(k, v, ttl)
+	return nil
+}
+
+func (i *inmemory) Delete(keys ...string) error {
+	for _, k := range keys {
+		i.tc.Delete(k)
+	}
+
 	return nil
 }
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCache_Capacity(t *testing.T) {
+	t.Parallel()
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	size := func() uint64 {
+		v, err := toJSON(res)
+		require.NoError(t, err)
+
+		return uint64(len("k1") + len(v))
+	}()
+
+	for _, tc := range []struct {
+		name       string
+		maxEntries uint64
+		maxBytes   uint64
+	}{
+		{name: "max entries", maxEntries: 2},
+		{name: "max bytes", maxBytes: 2 * size},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := NewInMemoryCache(60, tc.maxEntries, tc.maxBytes)
+
+			require.NoError(t, c.Set("k1", res, 0))
+			require.NoError(t, c.Set("k2", res, 0))
+
+			// Reading k1 makes k2 the least recently used entry.
+			_, ok, err := c.Get("k1")
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			require.NoError(t, c.Set("k3", res, 0))
+
+			for key, want := range map[string]bool{"k1": true, "k2": false, "k3": true} {
+				_, ok, err := c.Get(key)
+				require.NoError(t, err)
+				require.Equal(t, want, ok, key)
+			}
+		})
+	}
+}
+
+func TestInMemoryCache_EvictionMetrics(t *testing.T) {
+	t.Parallel()
+
+	c := NewInMemoryCache(60, 1, 0)
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, c.Set("k1", res, 0))
+	require.NoError(t, c.Set("k2", res, time.Millisecond))
+
+	want := `
+# HELP opa_openshift_cache_evictions_total Counts the number of cache evictions.
+# TYPE opa_openshift_cache_evictions_total counter
+opa_openshift_cache_evictions_total{reason="capacity"} 1
+opa_openshift_cache_evictions_total{reason="expired"} 1
+`
+
+	require.Eventually(t, func() bool {
+		return testutil.CollectAndCompare(c, strings.NewReader(want), "opa_openshift_cache_evictions_total") == nil
+	}, time.Second, 10*time.Millisecond)
+}
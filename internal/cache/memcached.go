@@ -2,50 +2,142 @@ package cache
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
+	gomemcache "github.com/bradfitz/gomemcache/memcache"
 	"github.com/open-policy-agent/opa/v1/server/types"
-	tcache "github.com/openshift/telemeter/pkg/cache"
-	"github.com/openshift/telemeter/pkg/cache/memcached"
 )
 
 type memcache struct {
-	mc tcache.Cacher
+	mu         sync.RWMutex
+	client     *gomemcache.Client
+	expiration int32
+
+	*remoteMetrics
 }
 
-func NewMemached(ctx context.Context, interval, expiration int32, servers ...string) Cacher {
-	mc := memcached.New(ctx, interval, expiration, servers...)
+// NewMemached creates a new Cacher from a list of Memcached servers, a default
+// key expiration given in seconds and a DNS refresh interval given in seconds.
+// The Memcached server addresses are resolved again every interval as long as
+// the context is valid.
+func NewMemached(ctx context.Context, interval, expiration int32, servers ...string) CacherWithMetrics {
+	m := &memcache{
+		client:        gomemcache.New(servers...),
+		expiration:    expiration,
+		remoteMetrics: newRemoteMetrics(),
+	}
 
-	return &memcache{mc: mc}
+	if interval > 0 {
+		go m.refresh(ctx, time.Duration(interval)*time.Second, servers)
+	}
+
+	return m
+}
+
+func (m *memcache) refresh(ctx context.Context, interval time.Duration, servers []string) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			m.mu.Lock()
+			m.client = gomemcache.New(servers...)
+			m.mu.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
 }
 
 func (m *memcache) Get(k string) (types.DataResponseV1, bool, error) {
-	b, ok, err := m.mc.Get(k)
+	start := time.Now()
+
+	m.mu.RLock()
+	item, err := m.client.Get(hashKey(k))
+	m.mu.RUnlock()
+
+	m.observeDuration(metricsOperationGet, start)
+
+	if errors.Is(err, gomemcache.ErrCacheMiss) {
+		m.misses.Add(1)
+		return types.DataResponseV1{}, false, nil
+	}
+
 	if err != nil {
+		m.getErrors.Add(1)
 		return types.DataResponseV1{}, false, fmt.Errorf("failed to fetch from memcached: %w", err)
 	}
 
-	if ok {
-		res, err := fromJSON(b)
-		if err != nil {
-			return types.DataResponseV1{}, false, err
-		}
+	m.hit(len(item.Value))
 
-		return res, true, nil
+	res, err := fromJSON(item.Value)
+	if err != nil {
+		return types.DataResponseV1{}, false, err
 	}
 
-	return types.DataResponseV1{}, false, nil
+	return res, true, nil
 }
 
-func (m *memcache) Set(k string, res types.DataResponseV1) error {
+func (m *memcache) Set(k string, res types.DataResponseV1, ttl time.Duration) error {
 	v, err := toJSON(res)
 	if err != nil {
 		return err
 	}
 
-	if err := m.mc.Set(k, v); err != nil {
+	item := &gomemcache.Item{
+		Key:        hashKey(k),
+		Value:      v,
+		Expiration: m.expirationFor(ttl),
+	}
+
+	start := time.Now()
+
+	m.mu.RLock()
+	err = m.client.Set(item)
+	m.mu.RUnlock()
+
+	m.observeDuration(metricsOperationSet, start)
+
+	if err != nil {
+		m.setErrors.Add(1)
 		return fmt.Errorf("failed to store in memcached: %w", err)
 	}
 
+	m.insert(len(v))
+
+	return nil
+}
+
+func (m *memcache) Delete(keys ...string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, k := range keys {
+		if err := m.client.Delete(hashKey(k)); err != nil && !errors.Is(err, gomemcache.ErrCacheMiss) {
+			return fmt.Errorf("failed to delete from memcached: %w", err)
+		}
+	}
+
 	return nil
 }
+
+// expirationFor converts the given TTL into a Memcached expiration in seconds,
+// rounding up so that short TTLs do not turn into a never-expiring zero.
+func (m *memcache) expirationFor(ttl time.Duration) int32 {
+	if ttl <= 0 {
+		return m.expiration
+	}
+
+	return int32((ttl + time.Second - 1) / time.Second)
+}
+
+// hashKey hashes the given key to ensure that it is less than 250 bytes,
+// as Memcached cannot handle longer keys.
+func hashKey(k string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(k)))
+}
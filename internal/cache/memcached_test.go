@@ -0,0 +1,213 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type memcachedItem struct {
+	value      []byte
+	expiration int
+}
+
+// fakeMemcached serves the subset of the Memcached text protocol used by the backend.
+type fakeMemcached struct {
+	ln net.Listener
+
+	mu    sync.Mutex
+	items map[string]memcachedItem
+}
+
+func newFakeMemcached(t *testing.T) *fakeMemcached {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	f := &fakeMemcached{ln: ln, items: map[string]memcachedItem{}}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go f.serve(conn)
+		}
+	}()
+
+	return f
+}
+
+func (f *fakeMemcached) addr() string {
+	return f.ln.Addr().String()
+}
+
+func (f *fakeMemcached) item(key string) (memcachedItem, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	item, ok := f.items[hashKey(key)]
+
+	return item, ok
+}
+
+func (f *fakeMemcached) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return
+		}
+
+		f.mu.Lock()
+
+		switch fields[0] {
+		case "gets", "get":
+			for _, key := range fields[1:] {
+				if item, ok := f.items[key]; ok {
+					fmt.Fprintf(rw, "VALUE %s 0 %d 1\r\n%s\r\n", key, len(item.value), item.value)
+				}
+			}
+
+			fmt.Fprint(rw, "END\r\n")
+		case "set":
+			exp, _ := strconv.Atoi(fields[3])
+			size, _ := strconv.Atoi(fields[4])
+
+			value := make([]byte, size+2)
+			if _, err := io.ReadFull(rw, value); err != nil {
+				f.mu.Unlock()
+				return
+			}
+
+			f.items[fields[1]] = memcachedItem{value: value[:size], expiration: exp}
+			fmt.Fprint(rw, "STORED\r\n")
+		case "delete":
+			if _, ok := f.items[fields[1]]; ok {
+				delete(f.items, fields[1])
+				fmt.Fprint(rw, "DELETED\r\n")
+			} else {
+				fmt.Fprint(rw, "NOT_FOUND\r\n")
+			}
+		default:
+			fmt.Fprint(rw, "ERROR\r\n")
+		}
+
+		f.mu.Unlock()
+
+		if err := rw.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func TestMemcached_TTL(t *testing.T) {
+	f := newFakeMemcached(t)
+	c := NewMemached(context.Background(), 0, 60, f.addr())
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, c.Set("default", res, 0))
+	require.NoError(t, c.Set("short", res, 1500*time.Millisecond))
+
+	item, ok := f.item("default")
+	require.True(t, ok)
+	require.Equal(t, 60, item.expiration)
+
+	item, ok = f.item("short")
+	require.True(t, ok)
+	require.Equal(t, 2, item.expiration)
+
+	got, ok, err := c.Get("short")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, res, got)
+}
+
+func TestMemcached_Delete(t *testing.T) {
+	f := newFakeMemcached(t)
+	c := NewMemached(context.Background(), 0, 60, f.addr())
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	for _, k := range []string{"a", "b", "c"} {
+		require.NoError(t, c.Set(k, res, 0))
+	}
+
+	require.NoError(t, c.Delete("a", "missing"))
+
+	_, ok := f.item("a")
+	require.False(t, ok)
+	_, ok = f.item("b")
+	require.True(t, ok)
+}
+
+func TestMemcached_Metrics(t *testing.T) {
+	f := newFakeMemcached(t)
+	c := NewMemached(context.Background(), 0, 60, f.addr())
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, c.Set("key", res, 0))
+
+	_, ok, err := c.Get("key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, ok, err = c.Get("missing")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	unavailable := NewMemached(context.Background(), 0, 60, "127.0.0.1:1")
+	_, _, err = unavailable.Get("key")
+	require.Error(t, err)
+
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP opa_openshift_cache_errors_total Counts the number of failed cache operations.
+# TYPE opa_openshift_cache_errors_total counter
+opa_openshift_cache_errors_total{operation="get"} 0
+opa_openshift_cache_errors_total{operation="set"} 0
+# HELP opa_openshift_cache_inserts_total Counts the number of inserts into the cache.
+# TYPE opa_openshift_cache_inserts_total counter
+opa_openshift_cache_inserts_total 1
+# HELP opa_openshift_cache_requests_total Counts the number of retrieval requests to the cache.
+# TYPE opa_openshift_cache_requests_total counter
+opa_openshift_cache_requests_total{result="hit"} 1
+opa_openshift_cache_requests_total{result="miss"} 1
+`), "opa_openshift_cache_errors_total", "opa_openshift_cache_inserts_total", "opa_openshift_cache_requests_total"))
+
+	require.NoError(t, testutil.CollectAndCompare(unavailable, strings.NewReader(`
+# HELP opa_openshift_cache_errors_total Counts the number of failed cache operations.
+# TYPE opa_openshift_cache_errors_total counter
+opa_openshift_cache_errors_total{operation="get"} 1
+opa_openshift_cache_errors_total{operation="set"} 0
+`), "opa_openshift_cache_errors_total"))
+
+	require.Equal(t, 4, testutil.CollectAndCount(c, "opa_openshift_cache_payload_size_bytes", "opa_openshift_cache_operation_duration_seconds"))
+}
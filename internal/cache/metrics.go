@@ -1,19 +1,35 @@
 package cache
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 const (
 	metricsPrefix            = "opa_openshift_cache_"
 	metricNameCacheRequests  = metricsPrefix + "requests_total"
 	metricNameCacheInserts   = metricsPrefix + "inserts_total"
 	metricNameCacheEvictions = metricsPrefix + "evictions_total"
+	metricNameCacheErrors    = metricsPrefix + "errors_total"
+	metricNameCachePayload   = metricsPrefix + "payload_size_bytes"
+	metricNameCacheDuration  = metricsPrefix + "operation_duration_seconds"
 
 	metricsRequestResultHit  = "hit"
 	metricsRequestResultMiss = "miss"
+
+	metricsEvictionReasonExpired  = "expired"
+	metricsEvictionReasonCapacity = "capacity"
+
+	metricsOperationGet = "get"
+	metricsOperationSet = "set"
 )
 
 var (
-	metricsLabels = []string{"result"}
+	metricsLabels          = []string{"result"}
+	metricsEvictionLabels  = []string{"reason"}
+	metricsOperationLabels = []string{"operation"}
 
 	descCacheRequests = prometheus.NewDesc(
 		metricNameCacheRequests,
@@ -26,5 +42,76 @@ var (
 	descCacheEvictions = prometheus.NewDesc(
 		metricNameCacheEvictions,
 		"Counts the number of cache evictions.",
-		nil, nil)
+		metricsEvictionLabels, nil)
+	descCacheErrors = prometheus.NewDesc(
+		metricNameCacheErrors,
+		"Counts the number of failed cache operations.",
+		metricsOperationLabels, nil)
 )
+
+func newPayloadSizeHistogram() *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    metricNameCachePayload,
+		Help:    "A histogram of the size of the entries read from and written to the cache.",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 8), //nolint:gomnd
+	}, metricsOperationLabels)
+}
+
+func newOperationDurationHistogram() *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    metricNameCacheDuration,
+		Help:    "A histogram of the latency of cache operations.",
+		Buckets: []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}, //nolint:gomnd
+	}, metricsOperationLabels)
+}
+
+// remoteMetrics counts the operations of the caches backed by a remote server.
+type remoteMetrics struct {
+	hits, misses, inserts atomic.Uint64
+	getErrors, setErrors  atomic.Uint64
+	payloadSize           *prometheus.HistogramVec
+	duration              *prometheus.HistogramVec
+}
+
+func newRemoteMetrics() *remoteMetrics {
+	return &remoteMetrics{
+		payloadSize: newPayloadSizeHistogram(),
+		duration:    newOperationDurationHistogram(),
+	}
+}
+
+func (m *remoteMetrics) observeDuration(operation string, start time.Time) {
+	m.duration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func (m *remoteMetrics) hit(size int) {
+	m.hits.Add(1)
+	m.payloadSize.WithLabelValues(metricsOperationGet).Observe(float64(size))
+}
+
+func (m *remoteMetrics) insert(size int) {
+	m.inserts.Add(1)
+	m.payloadSize.WithLabelValues(metricsOperationSet).Observe(float64(size))
+}
+
+func (m *remoteMetrics) Describe(descs chan<- *prometheus.Desc) {
+	descs <- descCacheInserts
+	descs <- descCacheRequests
+	descs <- descCacheErrors
+	m.payloadSize.Describe(descs)
+	m.duration.Describe(descs)
+}
+
+func (m *remoteMetrics) Collect(metricsCh chan<- prometheus.Metric) {
+	metricsCh <- prometheus.MustNewConstMetric(descCacheInserts, prometheus.CounterValue, float64(m.inserts.Load()))
+	metricsCh <- prometheus.MustNewConstMetric(descCacheRequests,
+		prometheus.CounterValue, float64(m.hits.Load()), metricsRequestResultHit)
+	metricsCh <- prometheus.MustNewConstMetric(descCacheRequests,
+		prometheus.CounterValue, float64(m.misses.Load()), metricsRequestResultMiss)
+	metricsCh <- prometheus.MustNewConstMetric(descCacheErrors,
+		prometheus.CounterValue, float64(m.getErrors.Load()), metricsOperationGet)
+	metricsCh <- prometheus.MustNewConstMetric(descCacheErrors,
+		prometheus.CounterValue, float64(m.setErrors.Load()), metricsOperationSet)
+	m.payloadSize.Collect(metricsCh)
+	m.duration.Collect(metricsCh)
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisOptions configures the connection to a standalone Redis server,
+// a Redis Sentinel deployment or a Redis Cluster.
+type RedisOptions struct {
+	// Addrs are the addresses of the Redis server, the Sentinels or the Cluster nodes.
+	Addrs []string
+	// MasterName is the name of the master monitored by the Sentinels given in Addrs.
+	MasterName string
+	// Cluster connects to a Redis Cluster even if only a single address is given,
+	// e.g. a configuration endpoint. Several addresses without a MasterName always
+	// denote a Cluster.
+	Cluster bool
+	// DB is the database selected on a standalone or Sentinel-managed server.
+	DB       int
+	Username string
+	Password string
+	// TLSConfig enables TLS when not nil.
+	TLSConfig *tls.Config
+	// Timeout bounds every single cache operation.
+	Timeout time.Duration
+	// Expiration is applied to entries stored without a TTL.
+	Expiration time.Duration
+}
+
+type redisCache struct {
+	client     redis.UniversalClient
+	timeout    time.Duration
+	expiration time.Duration
+
+	*remoteMetrics
+}
+
+// NewRedis creates a new Cacher storing entries in Redis.
+func NewRedis(opts RedisOptions) CacherWithMetrics {
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:         opts.Addrs,
+		MasterName:    opts.MasterName,
+		IsClusterMode: opts.Cluster,
+		DB:            opts.DB,
+		Username:      opts.Username,
+		Password:      opts.Password,
+		TLSConfig:     opts.TLSConfig,
+	})
+
+	return &redisCache{
+		client:        client,
+		timeout:       opts.Timeout,
+		expiration:    opts.Expiration,
+		remoteMetrics: newRemoteMetrics(),
+	}
+}
+
+func (r *redisCache) Get(k string) (types.DataResponseV1, bool, error) {
+	res, _, ok, err := r.GetWithTTL(k)
+
+	return res, ok, err
+}
+
+// GetWithTTL fetches the entry and its remaining TTL in a single round trip.
+func (r *redisCache) GetWithTTL(k string) (types.DataResponseV1, time.Duration, bool, error) {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	var (
+		get *redis.StringCmd
+		ttl *redis.DurationCmd
+	)
+
+	start := time.Now()
+	_, err := r.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		get = p.Get(ctx, hashKey(k))
+		ttl = p.PTTL(ctx, hashKey(k))
+
+		return nil
+	})
+
+	r.observeDuration(metricsOperationGet, start)
+
+	// A negative TTL other than -1 means the entry expired after it was fetched.
+	if errors.Is(err, redis.Nil) || ttl.Val() < -1 {
+		r.misses.Add(1)
+		return types.DataResponseV1{}, 0, false, nil
+	}
+
+	if err != nil {
+		r.getErrors.Add(1)
+		return types.DataResponseV1{}, 0, false, fmt.Errorf("failed to fetch from redis: %w", err)
+	}
+
+	v, _ := get.Bytes()
+	r.hit(len(v))
+
+	res, err := fromJSON(v)
+	if err != nil {
+		return types.DataResponseV1{}, 0, false, err
+	}
+
+	return res, max(ttl.Val(), 0), true, nil
+}
+
+func (r *redisCache) Set(k string, res types.DataResponseV1, ttl time.Duration) error {
+	v, err := toJSON(res)
+	if err != nil {
+		return err
+	}
+
+	if ttl <= 0 {
+		ttl = r.expiration
+	}
+
+	ctx, cancel := r.context()
+	defer cancel()
+
+	start := time.Now()
+	err = r.client.Set(ctx, hashKey(k), v, ttl).Err()
+
+	r.observeDuration(metricsOperationSet, start)
+
+	if err != nil {
+		r.setErrors.Add(1)
+		return fmt.Errorf("failed to store in redis: %w", err)
+	}
+
+	r.insert(len(v))
+
+	return nil
+}
+
+func (r *redisCache) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	ctx, cancel := r.context()
+	defer cancel()
+
+	// Keys of a Redis Cluster may live on different nodes, so they are deleted one by one.
+	for _, k := range keys {
+		if err := r.client.Del(ctx, hashKey(k)).Err(); err != nil {
+			return fmt.Errorf("failed to delete from redis: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *redisCache) context() (context.Context, context.CancelFunc) {
+	if r.timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), r.timeout)
+}
@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedis_TTL(t *testing.T) {
+	t.Parallel()
+
+	s := miniredis.RunT(t)
+	c := NewRedis(RedisOptions{Addrs: []string{s.Addr()}, Expiration: time.Minute})
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, c.Set("default", res, 0))
+	require.NoError(t, c.Set("short", res, 5*time.Second))
+
+	require.Equal(t, time.Minute, s.TTL(hashKey("default")))
+	require.Equal(t, 5*time.Second, s.TTL(hashKey("short")))
+
+	got, ok, err := c.Get("short")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, res, got)
+
+	s.FastForward(2 * time.Second)
+
+	got, ttl, ok, err := c.(TTLCacher).GetWithTTL("short")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, res, got)
+	require.Equal(t, 3*time.Second, ttl)
+
+	s.FastForward(3 * time.Second)
+
+	_, ok, err = c.Get("short")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, ok, err = c.Get("default")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestRedis_Auth(t *testing.T) {
+	t.Parallel()
+
+	s := miniredis.RunT(t)
+	s.RequireUserAuth("opa", "secret")
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	for _, tc := range []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{name: "valid credentials", password: "secret"},
+		{name: "invalid credentials", password: "wrong", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := NewRedis(RedisOptions{Addrs: []string{s.Addr()}, Username: "opa", Password: tc.password})
+
+			err := c.Set(tc.name, res, time.Minute)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRedis_TLS(t *testing.T) {
+	t.Parallel()
+
+	cert, pool := newTestCertificate(t)
+
+	s, err := miniredis.RunTLS(&tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+
+	c := NewRedis(RedisOptions{
+		Addrs:     []string{s.Addr()},
+		TLSConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+	})
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, c.Set("key", res, time.Minute))
+
+	got, ok, err := c.Get("key")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, res, got)
+}
+
+func TestRedis_Delete(t *testing.T) {
+	t.Parallel()
+
+	s := miniredis.RunT(t)
+	c := NewRedis(RedisOptions{Addrs: []string{s.Addr()}, Expiration: time.Minute})
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	for _, k := range []string{"a", "b", "c"} {
+		require.NoError(t, c.Set(k, res, 0))
+	}
+
+	require.NoError(t, c.Delete("a", "missing"))
+	require.False(t, s.Exists(hashKey("a")))
+	require.True(t, s.Exists(hashKey("b")))
+}
+
+func TestRedis_Metrics(t *testing.T) {
+	t.Parallel()
+
+	s := miniredis.RunT(t)
+	c := NewRedis(RedisOptions{Addrs: []string{s.Addr()}, Expiration: time.Minute})
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, c.Set("key", res, 0))
+
+	_, ok, err := c.Get("key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, ok, err = c.Get("missing")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	s.SetError("LOADING")
+
+	_, _, err = c.Get("key")
+	require.Error(t, err)
+	require.Error(t, c.Set("key", res, 0))
+
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP opa_openshift_cache_errors_total Counts the number of failed cache operations.
+# TYPE opa_openshift_cache_errors_total counter
+opa_openshift_cache_errors_total{operation="get"} 1
+opa_openshift_cache_errors_total{operation="set"} 1
+# HELP opa_openshift_cache_inserts_total Counts the number of inserts into the cache.
+# TYPE opa_openshift_cache_inserts_total counter
+opa_openshift_cache_inserts_total 1
+# HELP opa_openshift_cache_requests_total Counts the number of retrieval requests to the cache.
+# TYPE opa_openshift_cache_requests_total counter
+opa_openshift_cache_requests_total{result="hit"} 1
+opa_openshift_cache_requests_total{result="miss"} 1
+`), "opa_openshift_cache_errors_total", "opa_openshift_cache_inserts_total", "opa_openshift_cache_requests_total"))
+}
+
+// newTestCertificate returns a self-signed certificate for 127.0.0.1 and a pool trusting it.
+func newTestCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redis"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
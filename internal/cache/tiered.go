@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"errors"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsTierL1 = "l1"
+	metricsTierL2 = "l2"
+)
+
+type tiered struct {
+	l1, l2 Cacher
+	l1TTL  time.Duration
+
+	collectors []prometheus.Collector
+}
+
+// NewTiered creates a new Cacher reading through and writing through an in-process
+// L1 cache to the given L2 cache, typically a remote backend shared between replicas.
+// Entries are kept in L1 for at most l1TTL, bounding how long a replica may serve an
+// entry that was already replaced in L2, and for no longer than the TTL of the entry,
+// or its remaining TTL in L2 if the L2 cache is a TTLCacher. L1 holds at most
+// l1Capacity entries.
+// The metrics of both tiers are distinguished by a tier label.
+func NewTiered(l2 CacherWithMetrics, l1TTL time.Duration, l1Capacity uint64) CacherWithMetrics {
+	l1 := NewInMemoryCache(int32((l1TTL+time.Second-1)/time.Second), l1Capacity, 0)
+
+	return &tiered{
+		l1:    l1,
+		l2:    l2,
+		l1TTL: l1TTL,
+		collectors: []prometheus.Collector{
+			prometheus.WrapCollectorWith(prometheus.Labels{"tier": metricsTierL1}, l1),
+			prometheus.WrapCollectorWith(prometheus.Labels{"tier": metricsTierL2}, l2),
+		},
+	}
+}
+
+func (t *tiered) Describe(descs chan<- *prometheus.Desc) {
+	for _, c := range t.collectors {
+		c.Describe(descs)
+	}
+}
+
+func (t *tiered) Collect(metricsCh chan<- prometheus.Metric) {
+	for _, c := range t.collectors {
+		c.Collect(metricsCh)
+	}
+}
+
+func (t *tiered) Get(k string) (types.DataResponseV1, bool, error) {
+	if res, ok, err := t.l1.Get(k); err == nil && ok {
+		return res, true, nil
+	}
+
+	// The entry is kept in L1 for no longer than it remains in L2, if known.
+	res, ttl, ok, err := getWithTTL(t.l2, k)
+	if err != nil || !ok {
+		return types.DataResponseV1{}, false, err
+	}
+
+	if err := t.l1.Set(k, res, t.l1TTLFor(ttl)); err != nil {
+		return types.DataResponseV1{}, false, err
+	}
+
+	return res, true, nil
+}
+
+func (t *tiered) Set(k string, res types.DataResponseV1, ttl time.Duration) error {
+	if err := t.l2.Set(k, res, ttl); err != nil {
+		return err
+	}
+
+	return t.l1.Set(k, res, t.l1TTLFor(ttl))
+}
+
+// Delete always clears L1, so that a failure of L2 does not leave the entries
+// served from L1 by this replica.
+func (t *tiered) Delete(keys ...string) error {
+	err := t.l2.Delete(keys...)
+
+	return errors.Join(err, t.l1.Delete(keys...))
+}
+
+// l1TTLFor caps the TTL of an entry in L1 to l1TTL.
+func (t *tiered) l1TTLFor(ttl time.Duration) time.Duration {
+	if ttl <= 0 || ttl > t.l1TTL {
+		return t.l1TTL
+	}
+
+	return ttl
+}
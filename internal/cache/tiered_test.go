@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTiered_ReadThrough(t *testing.T) {
+	t.Parallel()
+
+	s := miniredis.RunT(t)
+	l2 := NewRedis(RedisOptions{Addrs: []string{s.Addr()}, Expiration: time.Minute})
+	c := NewTiered(l2, time.Minute, 0)
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, l2.Set("key", res, 0))
+
+	got, ok, err := c.Get("key")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, res, got)
+
+	// The entry is now served from L1 without reaching L2.
+	s.FlushAll()
+
+	got, ok, err = c.Get("key")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, res, got)
+
+	_, ok, err = c.Get("missing")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestTiered_ReadThroughTTL(t *testing.T) {
+	t.Parallel()
+
+	s := miniredis.RunT(t)
+	l2 := NewRedis(RedisOptions{Addrs: []string{s.Addr()}, Expiration: time.Minute})
+	c := NewTiered(l2, time.Minute, 0)
+
+	var denied interface{} = false
+	res := types.DataResponseV1{Result: &denied}
+
+	// A short-lived entry stored by another replica is not kept longer in L1.
+	require.NoError(t, l2.Set("key", res, 50*time.Millisecond))
+
+	_, ok, err := c.Get("key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	s.FlushAll()
+
+	require.Eventually(t, func() bool {
+		_, ok, err := c.Get("key")
+		return err == nil && !ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTiered_WriteThrough(t *testing.T) {
+	t.Parallel()
+
+	s := miniredis.RunT(t)
+	l2 := NewRedis(RedisOptions{Addrs: []string{s.Addr()}, Expiration: time.Minute})
+	c := NewTiered(l2, 10*time.Second, 0)
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, c.Set("key", res, 5*time.Minute))
+	require.Equal(t, 5*time.Minute, s.TTL(hashKey("key")))
+
+	got, ok, err := l2.Get("key")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, res, got)
+}
+
+func TestTiered_Delete(t *testing.T) {
+	t.Parallel()
+
+	s := miniredis.RunT(t)
+	l2 := NewRedis(RedisOptions{Addrs: []string{s.Addr()}, Expiration: time.Minute})
+	c := NewTiered(l2, time.Minute, 0)
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, c.Set("a", res, 0))
+	require.NoError(t, c.Set("b", res, 0))
+
+	require.NoError(t, c.Delete("a"))
+	require.False(t, s.Exists(hashKey("a")))
+
+	_, ok, err := c.Get("a")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, ok, err = c.Get("b")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestTiered_DeleteL2Failure(t *testing.T) {
+	t.Parallel()
+
+	s := miniredis.RunT(t)
+	l2 := NewRedis(RedisOptions{Addrs: []string{s.Addr()}, Expiration: time.Minute})
+	c := NewTiered(l2, time.Minute, 0)
+	l1 := c.(*tiered).l1
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, c.Set("a", res, 0))
+
+	s.SetError("unavailable")
+
+	require.Error(t, c.Delete("a"))
+
+	_, ok, err := l1.Get("a")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestTiered_Capacity(t *testing.T) {
+	t.Parallel()
+
+	s := miniredis.RunT(t)
+	l2 := NewRedis(RedisOptions{Addrs: []string{s.Addr()}, Expiration: time.Minute})
+	c := NewTiered(l2, time.Minute, 1)
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, c.Set("first", res, 0))
+	require.NoError(t, c.Set("second", res, 0))
+
+	s.FlushAll()
+
+	_, ok, err := c.Get("first")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, ok, err = c.Get("second")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestTiered_L1TTL(t *testing.T) {
+	t.Parallel()
+
+	c := &tiered{l1TTL: 10 * time.Second}
+
+	for _, tc := range []struct {
+		name string
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{name: "default", ttl: 0, want: 10 * time.Second},
+		{name: "shorter", ttl: 5 * time.Second, want: 5 * time.Second},
+		{name: "longer", ttl: time.Minute, want: 10 * time.Second},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, c.l1TTLFor(tc.ttl))
+		})
+	}
+}
+
+func TestTiered_Metrics(t *testing.T) {
+	t.Parallel()
+
+	s := miniredis.RunT(t)
+	l2 := NewRedis(RedisOptions{Addrs: []string{s.Addr()}, Expiration: time.Minute})
+	c := NewTiered(l2, time.Minute, 0)
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, l2.Set("key", res, 0))
+
+	for range 3 {
+		_, ok, err := c.Get("key")
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP opa_openshift_cache_inserts_total Counts the number of inserts into the cache.
+# TYPE opa_openshift_cache_inserts_total counter
+opa_openshift_cache_inserts_total{tier="l1"} 1
+opa_openshift_cache_inserts_total{tier="l2"} 1
+# HELP opa_openshift_cache_requests_total Counts the number of retrieval requests to the cache.
+# TYPE opa_openshift_cache_requests_total counter
+opa_openshift_cache_requests_total{result="hit",tier="l1"} 2
+opa_openshift_cache_requests_total{result="hit",tier="l2"} 1
+opa_openshift_cache_requests_total{result="miss",tier="l1"} 1
+opa_openshift_cache_requests_total{result="miss",tier="l2"} 0
+`), "opa_openshift_cache_inserts_total", "opa_openshift_cache_requests_total"))
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/server/types"
+)
+
+// Outcome classifies an authorization decision stored in the cache.
+type Outcome string
+
+const (
+	// OutcomeAllowed is a decision granting access without restricting matchers.
+	OutcomeAllowed Outcome = "allowed"
+	// OutcomePartial is a decision granting access restricted by label matchers.
+	OutcomePartial Outcome = "partial"
+	// OutcomeDenied is a decision denying access.
+	OutcomeDenied Outcome = "denied"
+)
+
+// OutcomeOf classifies the given authorization decision.
+func OutcomeOf(res types.DataResponseV1) Outcome {
+	if res.Result == nil {
+		return OutcomeDenied
+	}
+
+	var allowed interface{}
+
+	switch r := (*res.Result).(type) {
+	case bool:
+		if r {
+			return OutcomeAllowed
+		}
+
+		return OutcomeDenied
+	case map[string]string:
+		allowed = r["allowed"]
+	case map[string]interface{}:
+		allowed = r["allowed"]
+	}
+
+	if allowed == "true" {
+		return OutcomePartial
+	}
+
+	return OutcomeDenied
+}
+
+// TTLs holds the time-to-live applied to cached decisions depending on their outcome.
+// A zero duration leaves the expiration to the default of the cache backend.
+type TTLs struct {
+	Allowed time.Duration
+	Partial time.Duration
+	Denied  time.Duration
+}
+
+// For returns the time-to-live for the given authorization decision.
+func (t TTLs) For(res types.DataResponseV1) time.Duration {
+	switch OutcomeOf(res) {
+	case OutcomeAllowed:
+		return t.Allowed
+	case OutcomePartial:
+		return t.Partial
+	default:
+		return t.Denied
+	}
+}
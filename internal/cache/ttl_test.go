@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLsFor(t *testing.T) {
+	ttls := TTLs{Allowed: 5 * time.Minute, Partial: time.Minute, Denied: 10 * time.Second}
+
+	tt := []struct {
+		desc        string
+		result      interface{}
+		wantOutcome Outcome
+		wantTTL     time.Duration
+	}{
+		{
+			desc:        "allowed",
+			result:      true,
+			wantOutcome: OutcomeAllowed,
+			wantTTL:     5 * time.Minute,
+		},
+		{
+			desc:        "denied",
+			result:      false,
+			wantOutcome: OutcomeDenied,
+			wantTTL:     10 * time.Second,
+		},
+		{
+			desc:        "partially allowed",
+			result:      map[string]string{"allowed": "true", "data": "{}"},
+			wantOutcome: OutcomePartial,
+			wantTTL:     time.Minute,
+		},
+		{
+			desc:        "denied with matchers",
+			result:      map[string]string{"allowed": "false", "data": "{}"},
+			wantOutcome: OutcomeDenied,
+			wantTTL:     10 * time.Second,
+		},
+		{
+			desc:        "partially allowed from cache",
+			result:      map[string]interface{}{"allowed": "true", "data": "{}"},
+			wantOutcome: OutcomePartial,
+			wantTTL:     time.Minute,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			res := types.DataResponseV1{Result: &tc.result}
+			require.Equal(t, tc.wantOutcome, OutcomeOf(res))
+			require.Equal(t, tc.wantTTL, ttls.For(res))
+		})
+	}
+}
+
+func TestInMemoryCacheTTL(t *testing.T) {
+	c := NewInMemoryCache(60, 0, 0)
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	require.NoError(t, c.Set("short", res, time.Millisecond))
+	require.NoError(t, c.Set("default", res, 0))
+
+	time.Sleep(10 * time.Millisecond)
+
+	_, ok, err := c.Get("short")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, ok, err = c.Get("default")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
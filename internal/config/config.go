@@ -4,8 +4,10 @@ import (
 	"errors"
 	"fmt"
 	stdlog "log"
+	"maps"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/go-kit/log/level"
 	flag "github.com/spf13/pflag"
@@ -18,8 +20,17 @@ var (
 	errInvalidOPAPackage  = errors.New("invalid OPA package name")
 	errInvalidOPARule     = errors.New("invalid OPA rule name")
 	errInvalidMapping     = errors.New("invalid mapping")
-	errViaQOTELMatcher    = errors.New("OPA matcher must contain both 'kubernetes_namespace_name' and 'k8s_namespace_name' when ViaQ to OTel migration is enabled")
+	errInvalidConcurrency = errors.New("invalid SAR concurrency")
+	errInvalidPoolSize    = errors.New("invalid client pool size")
 	errUnexpectedLogLevel = errors.New("unexpected log level")
+	errInvalidAuditSink   = errors.New("invalid audit sink")
+	errInvalidSampleRate  = errors.New("invalid audit sample rate")
+	errInvalidTraceRatio  = errors.New("invalid tracing sampling ratio")
+	errInvalidDecisionLog = errors.New("invalid decision log setting")
+	errConflictingCaches  = errors.New("only one of --memcached and --redis can be set")
+	errConflictingRedis   = errors.New("only one of --redis.master-name and --redis.cluster can be set")
+	errInvalidRulesReview = errors.New("rules reviews require self subject access reviews")
+	errMissingSubjectKey  = errors.New("--web.internal.admin-token-file requires --cache.subject-key-file")
 )
 
 type Config struct {
@@ -27,30 +38,59 @@ type Config struct {
 	DebugToken     string
 	Name           string
 	Mappings       map[string]string
+	ConfigFile     string
+	// Tenants holds the tenants of the configuration file.
+	Tenants map[string]Tenant
+
+	// flagMappings and flags are kept to resolve the tenants again on reload.
+	flagMappings map[string]string
+	flags        *flag.FlagSet
+	// matcher is the label matcher resolved with the tenants of the configuration file.
+	matcher *Matcher
 
 	LogFormat string
 	LogLevel  level.Option
 
 	Opa       OPAConfig
+	OpenShift OpenShiftConfig
 	Server    ServerConfig
 	TLS       TLSConfig
+	Cache     CacheConfig
 	Memcached MemcachedConfig
+	Redis     RedisConfig
+	Audit     AuditConfig
+
+	DecisionLogs DecisionLogsConfig
+	Tracing      TracingConfig
 }
 
 type OPAConfig struct {
-	Pkg                 string
-	Rule                string
-	Matcher             string
-	MatcherOp           string
-	MatcherSkipTenants  string
-	MatcherAdminGroups  string
-	SSAR                bool
-	ViaQToOTELMigration bool
+	Pkg                   string
+	Rule                  string
+	Matcher               string
+	MatcherOp             string
+	MatcherSkipTenants    string
+	MatcherAdminGroups    string
+	MatcherSingleEqual    bool
+	MatcherFactorPrefixes bool
+	MatcherMaxSize        int
+	SSAR                  bool
+	SSRR                  bool
+	ViaQToOTELMigration   bool
+	LabelAliases          []string
+}
+
+type OpenShiftConfig struct {
+	ClientPoolSize  int
+	ClientPoolTTL   time.Duration
+	SARConcurrency  int
+	DecisionTimeout time.Duration
 }
 
 type ServerConfig struct {
 	Listen         string
 	ListenInternal string
+	AdminTokenFile string
 	HealthcheckURL string
 }
 
@@ -66,13 +106,92 @@ type TLSConfig struct {
 	InternalServerCAFile   string
 }
 
+type CacheConfig struct {
+	AllowTTL   time.Duration
+	PartialTTL time.Duration
+	DenyTTL    time.Duration
+
+	NamespaceReviews bool
+
+	MaxEntries uint64
+	MaxBytes   uint64
+
+	L1TTL      time.Duration
+	L1Capacity uint64
+
+	EncryptionKeyFile string
+
+	StaleWhileRevalidate time.Duration
+
+	RBACWatch bool
+
+	IndexCapacity  uint64
+	SubjectKeyFile string
+}
+
 type MemcachedConfig struct {
 	Expire   int32
 	Interval int32
 	Servers  []string
 }
 
-//nolint:cyclop
+type RedisConfig struct {
+	Addrs        []string
+	MasterName   string
+	Cluster      bool
+	DB           int
+	Username     string
+	PasswordFile string
+	Expire       time.Duration
+	Timeout      time.Duration
+
+	TLSEnabled            bool
+	TLSCAFile             string
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSServerName         string
+	TLSInsecureSkipVerify bool
+}
+
+const (
+	AuditSinkStderr  = "stderr"
+	AuditSinkFile    = "file"
+	AuditSinkWebhook = "webhook"
+)
+
+type AuditConfig struct {
+	Sink       string
+	SampleRate float64
+
+	FilePath       string
+	FileMaxSize    int64
+	FileMaxBackups int
+
+	WebhookURL       string
+	WebhookTimeout   time.Duration
+	WebhookQueueSize int
+}
+
+type DecisionLogsConfig struct {
+	URL           string
+	BufferSize    int
+	BatchSize     int
+	FlushInterval time.Duration
+	Timeout       time.Duration
+	MaxRetries    int
+	RetryBackoff  time.Duration
+	SpoolDir      string
+	MaxSpoolFiles int
+}
+
+type TracingConfig struct {
+	Endpoint      string
+	Insecure      bool
+	SamplingRatio float64
+	ServiceName   string
+}
+
+//nolint:cyclop,funlen
 func ParseFlags() (*Config, error) {
 	var rawTLSCipherSuites string
 
@@ -82,9 +201,12 @@ func ParseFlags() (*Config, error) {
 	logLevelRaw := flag.String("log.level", "info", "The log filtering level. Options: 'error', 'warn', 'info', 'debug'.")
 	flag.StringVar(&cfg.LogFormat, "log.format", "logfmt", "The log format to use. Options: 'logfmt', 'json'.")
 
+	flag.StringVar(&cfg.ConfigFile, "config.file", "", "A YAML or JSON file holding per-tenant settings; flags given on the command line override them.") //nolint:lll
+
 	// Server flags
 	flag.StringVar(&cfg.Server.Listen, "web.listen", ":8080", "The address on which the public server listens.")
 	flag.StringVar(&cfg.Server.ListenInternal, "web.internal.listen", ":8081", "The address on which the internal server listens.")
+	flag.StringVar(&cfg.Server.AdminTokenFile, "web.internal.admin-token-file", "", "File containing the bearer token protecting the endpoints inspecting and purging cached decisions on the internal server; leave blank to disable them.") //nolint:lll
 	flag.StringVar(&cfg.Server.HealthcheckURL, "web.healthchecks.url", "http://localhost:8080", "The URL against which to run healthchecks.")
 
 	flag.StringVar(&cfg.TLS.MinVersion, "tls.min-version", "VersionTLS13",
@@ -108,7 +230,11 @@ func ParseFlags() (*Config, error) {
 
 	// OpenShift API flags
 	flag.StringVar(&cfg.KubeconfigPath, "openshift.kubeconfig", "", "A path to the kubeconfig against to use for authorizing client requests.")
-	mappingsRaw := flag.StringSlice("openshift.mappings", nil, "A map of tenantIDs to resource api groups to check to apply a given role to a user, e.g. tenant-a=observatorium.openshift.io") //nolint:lll
+	mappingsRaw := flag.StringSlice("openshift.mappings", nil, "A map of tenantIDs to resource api groups to check to apply a given role to a user, e.g. tenant-a=observatorium.openshift.io")    //nolint:lll
+	flag.IntVar(&cfg.OpenShift.ClientPoolSize, "openshift.client-pool.size", 1000, "The maximum number of per-token OpenShift clients kept for reuse across requests. Must be positive.")         //nolint:lll,gomnd
+	flag.DurationVar(&cfg.OpenShift.ClientPoolTTL, "openshift.client-pool.ttl", 5*time.Minute, "The duration after which an unused per-token OpenShift client is dropped from the pool.")         //nolint:lll,gomnd
+	flag.IntVar(&cfg.OpenShift.SARConcurrency, "openshift.sar-concurrency", 10, "The maximum number of namespaced access reviews issued in parallel for a single request.")                       //nolint:lll,gomnd
+	flag.DurationVar(&cfg.OpenShift.DecisionTimeout, "openshift.decision-timeout", 0, "The maximum duration of the API server calls made for a single authorization decision; use 0 to disable.") //nolint:lll
 
 	// OPA flags
 	flag.StringVar(&cfg.Opa.Pkg, "opa.package", "", "The name of the OPA package that opa-openshift should implement, see https://www.openpolicyagent.org/docs/latest/policy-language/#packages.")                              //nolint:lll
@@ -117,13 +243,83 @@ func ParseFlags() (*Config, error) {
 	flag.StringVar(&cfg.Opa.MatcherOp, "opa.matcher-op", "", "When several matchers are supplied (coma-separated string), this is the logical operation to perform. Allowed values: 'and', 'or'.")                              //nolint:lll
 	flag.StringVar(&cfg.Opa.MatcherSkipTenants, "opa.skip-tenants", "", "Tenants for which the label matcher should not be set as comma-separated values.")
 	flag.StringVar(&cfg.Opa.MatcherAdminGroups, "opa.admin-groups", "", "Groups which should be treated as admins and cause the matcher to be omitted.")
+	flag.BoolVar(&cfg.Opa.MatcherSingleEqual, "opa.matcher-single-equal", false, "Return an equality label matcher instead of a regex when only a single namespace is allowed.")              //nolint:lll
+	flag.BoolVar(&cfg.Opa.MatcherFactorPrefixes, "opa.matcher-factor-prefixes", false, "Factor common prefixes of the allowed namespaces out of the regex label matcher to reduce its size.") //nolint:lll
+	flag.IntVar(&cfg.Opa.MatcherMaxSize, "opa.matcher-max-size", 0, "The maximum size in bytes of a label matcher; requests resulting in a larger matcher fail. Use 0 to disable.")           //nolint:lll
 	flag.BoolVar(&cfg.Opa.SSAR, "opa.ssar", false, "Use SelftSubjectAccessReview instead of SubjectAccessReview.")
-	flag.BoolVar(&cfg.Opa.ViaQToOTELMigration, "opa.viaq-to-otel-migration", false, "Enable the ViaQ to OTel migration.")
+	flag.BoolVar(&cfg.Opa.SSRR, "opa.ssrr", false, "Use SelfSubjectRulesReview to resolve namespaced access, falling back to access reviews when the returned rules are incomplete. Requires --opa.ssar.")                                                     //nolint:lll
+	flag.BoolVar(&cfg.Opa.ViaQToOTELMigration, "opa.viaq-to-otel-migration", false, "Enable the ViaQ to OTel migration, i.e. --opa.label-aliases=kubernetes_namespace_name,k8s_namespace_name.")                                                               //nolint:lll
+	flag.StringArrayVar(&cfg.Opa.LabelAliases, "opa.label-aliases", nil, "Comma-separated keys of the OPA matcher naming the same label, e.g. before and after a migration. Only the key selected by a query is returned, or the first one. Can be repeated.") //nolint:lll
+
+	// Cache flags
+	flag.DurationVar(&cfg.Cache.AllowTTL, "cache.ttl.allow", 0, "Time after which cached decisions allowing access should expire; use 0 to apply the expiry of the cache backend, i.e. --redis.expire for Redis and --memcached.expire for Memcached and the in-memory cache.")                               //nolint:lll
+	flag.DurationVar(&cfg.Cache.PartialTTL, "cache.ttl.partial", 0, "Time after which cached decisions allowing access to a subset of namespaces should expire; use 0 to apply the expiry of the cache backend, i.e. --redis.expire for Redis and --memcached.expire for Memcached and the in-memory cache.") //nolint:lll
+	flag.DurationVar(&cfg.Cache.DenyTTL, "cache.ttl.deny", 0, "Time after which cached decisions denying access should expire; use 0 to apply the expiry of the cache backend, i.e. --redis.expire for Redis and --memcached.expire for Memcached and the in-memory cache.")                                  //nolint:lll
+
+	flag.BoolVar(&cfg.Cache.NamespaceReviews, "cache.namespace-reviews", false, "Cache the outcome of every single namespaced access review, so that requests for overlapping namespaces reuse them.") //nolint:lll
+
+	flag.Uint64Var(&cfg.Cache.MaxEntries, "cache.in-memory.max-entries", 0, "The maximum number of decisions kept by the in-memory cache, evicting the least recently used first; use 0 to disable.")        //nolint:lll
+	flag.Uint64Var(&cfg.Cache.MaxBytes, "cache.in-memory.max-bytes", 0, "The maximum size in bytes of the decisions kept by the in-memory cache, evicting the least recently used first; use 0 to disable.") //nolint:lll
+
+	flag.DurationVar(&cfg.Cache.L1TTL, "cache.l1.ttl", 0, "Keep decisions read from or written to --memcached or --redis in memory for at most this duration; use 0 to disable the in-memory tier.") //nolint:lll
+	flag.Uint64Var(&cfg.Cache.L1Capacity, "cache.l1.capacity", 10000, "The maximum number of decisions kept in the in-memory tier in front of --memcached or --redis.")                              //nolint:lll,gomnd
+
+	flag.StringVar(&cfg.Cache.EncryptionKeyFile, "cache.encryption.key-file", "", "File containing base64 encoded AES-256 keys, one per line, to encrypt decisions stored in --memcached or --redis. The first key encrypts, all keys decrypt.") //nolint:lll
+
+	flag.DurationVar(&cfg.Cache.StaleWhileRevalidate, "cache.stale-while-revalidate", 0, "Keep serving cached decisions for up to this duration past their TTL while they are revalidated in the background; use 0 to disable.") //nolint:lll
+
+	flag.BoolVar(&cfg.Cache.RBACWatch, "cache.rbac-watch", false, "Watch Roles, RoleBindings, ClusterRoles, ClusterRoleBindings and Projects and stop serving cached decisions affected by their changes.") //nolint:lll
+
+	flag.Uint64Var(&cfg.Cache.IndexCapacity, "cache.index.capacity", 100000, "The maximum number of cache keys indexed by subject and tenant for --web.internal.admin-token-file.")                                                                       //nolint:lll,gomnd
+	flag.StringVar(&cfg.Cache.SubjectKeyFile, "cache.subject-key-file", "", "File containing the secret keying the digests of subjects in cache keys, required by --web.internal.admin-token-file. Changing it changes the cache keys of all decisions.") //nolint:lll
 
 	// Memcached flags
 	flag.StringSliceVar(&cfg.Memcached.Servers, "memcached", nil, "One or more Memcached server addresses.")
-	flag.Int32Var(&cfg.Memcached.Expire, "memcached.expire", 60, "Time after which keys stored in Memcached should expire, given in seconds.")                 //nolint:lll,gomnd
-	flag.Int32Var(&cfg.Memcached.Interval, "memcached.interval", 10, "The interval at which to update the Memcached DNS, given in seconds; use 0 to disable.") //nolint:lll,gomnd
+	flag.Int32Var(&cfg.Memcached.Expire, "memcached.expire", 60, "Time after which keys stored in Memcached or in the in-memory cache should expire, given in seconds.") //nolint:lll,gomnd
+	flag.Int32Var(&cfg.Memcached.Interval, "memcached.interval", 10, "The interval at which to update the Memcached DNS, given in seconds; use 0 to disable.")           //nolint:lll,gomnd
+
+	// Redis flags
+	flag.StringSliceVar(&cfg.Redis.Addrs, "redis", nil, "One or more addresses of a Redis server, of Redis Sentinels or of Redis Cluster nodes.")
+	flag.StringVar(&cfg.Redis.MasterName, "redis.master-name", "", "The name of the master monitored by the Redis Sentinels given in --redis.")
+	flag.BoolVar(&cfg.Redis.Cluster, "redis.cluster", false, "Connect to a Redis Cluster through a single address; several addresses without --redis.master-name always denote a Cluster.") //nolint:lll
+	flag.IntVar(&cfg.Redis.DB, "redis.db", 0, "The database to select on a standalone or Sentinel-managed Redis server.")
+	flag.StringVar(&cfg.Redis.Username, "redis.username", "", "The username to authenticate to Redis with.")
+	flag.StringVar(&cfg.Redis.PasswordFile, "redis.password-file", "", "File containing the password to authenticate to Redis with.")
+	flag.DurationVar(&cfg.Redis.Expire, "redis.expire", time.Minute, "Time after which keys stored in Redis should expire.")
+	flag.DurationVar(&cfg.Redis.Timeout, "redis.timeout", time.Second, "The timeout of a single Redis operation.")
+	flag.BoolVar(&cfg.Redis.TLSEnabled, "redis.tls.enabled", false, "Connect to Redis over TLS.")
+	flag.StringVar(&cfg.Redis.TLSCAFile, "redis.tls.ca-file", "", "File containing the TLS CA against which to verify Redis; the system certificates are used when blank.") //nolint:lll
+	flag.StringVar(&cfg.Redis.TLSCertFile, "redis.tls.cert-file", "", "File containing the x509 client certificate presented to Redis.")
+	flag.StringVar(&cfg.Redis.TLSKeyFile, "redis.tls.key-file", "", "File containing the x509 private key matching --redis.tls.cert-file.")
+	flag.StringVar(&cfg.Redis.TLSServerName, "redis.tls.server-name", "", "The server name to verify the Redis certificate against; the address host is used when blank.") //nolint:lll
+	flag.BoolVar(&cfg.Redis.TLSInsecureSkipVerify, "redis.tls.insecure-skip-verify", false, "Skip the verification of the Redis certificate.")
+
+	// Audit flags
+	flag.StringVar(&cfg.Audit.Sink, "audit.sink", "", "The sink receiving one audit event per decision. Options: 'stderr', 'file', 'webhook'. Leave blank to disable auditing.")   //nolint:lll
+	flag.Float64Var(&cfg.Audit.SampleRate, "audit.sample-rate", 1, "The fraction of allowed decisions to audit, between 0 and 1. Denied and failed decisions are always audited.") //nolint:lll
+	flag.StringVar(&cfg.Audit.FilePath, "audit.file.path", "", "The file audit events are appended to when --audit.sink=file.")
+	flag.Int64Var(&cfg.Audit.FileMaxSize, "audit.file.max-size", 100<<20, "The size in bytes after which the audit file is rotated; use 0 to disable rotation.") //nolint:lll,gomnd
+	flag.IntVar(&cfg.Audit.FileMaxBackups, "audit.file.max-backups", 3, "The number of rotated audit files to keep.")                                            //nolint:gomnd
+	flag.StringVar(&cfg.Audit.WebhookURL, "audit.webhook.url", "", "The URL audit events are posted to when --audit.sink=webhook.")
+	flag.DurationVar(&cfg.Audit.WebhookTimeout, "audit.webhook.timeout", 5*time.Second, "The timeout for posting a single audit event.")                       //nolint:gomnd
+	flag.IntVar(&cfg.Audit.WebhookQueueSize, "audit.webhook.queue-size", 1000, "The number of audit events queued for the webhook before events are dropped.") //nolint:lll,gomnd
+
+	// Decision log flags
+	flag.StringVar(&cfg.DecisionLogs.URL, "decision-logs.url", "", "The URL batches of OPA decision log events are posted to. Leave blank to disable decision logs.")                                //nolint:lll
+	flag.IntVar(&cfg.DecisionLogs.BufferSize, "decision-logs.buffer-size", 10000, "The number of decision log events buffered before new events are dropped.")                                       //nolint:lll,gomnd
+	flag.IntVar(&cfg.DecisionLogs.BatchSize, "decision-logs.batch-size", 100, "The maximum number of decision log events sent in a single request.")                                                 //nolint:lll,gomnd
+	flag.DurationVar(&cfg.DecisionLogs.FlushInterval, "decision-logs.flush-interval", 5*time.Second, "The interval at which buffered decision log events are sent.")                                 //nolint:lll,gomnd
+	flag.DurationVar(&cfg.DecisionLogs.Timeout, "decision-logs.timeout", 10*time.Second, "The timeout of a single decision log upload.")                                                             //nolint:lll,gomnd
+	flag.IntVar(&cfg.DecisionLogs.MaxRetries, "decision-logs.max-retries", 3, "The number of retries of a failed decision log upload before the batch is spooled.")                                  //nolint:lll,gomnd
+	flag.DurationVar(&cfg.DecisionLogs.RetryBackoff, "decision-logs.retry-backoff", time.Second, "The delay before the first retry of a failed upload, doubling for every retry.")                   //nolint:lll
+	flag.StringVar(&cfg.DecisionLogs.SpoolDir, "decision-logs.spool-dir", "", "The directory failed decision log batches are kept in until they can be resent. Leave blank to drop failed batches.") //nolint:lll
+	flag.IntVar(&cfg.DecisionLogs.MaxSpoolFiles, "decision-logs.spool-max-files", 1000, "The maximum number of spooled decision log batches, dropping the oldest first.")                            //nolint:lll,gomnd
+
+	// Tracing flags
+	flag.StringVar(&cfg.Tracing.Endpoint, "tracing.endpoint", "", "The OTLP/HTTP endpoint spans are exported to, e.g. 'otel-collector:4318'. Leave blank to disable tracing.") //nolint:lll
+	flag.BoolVar(&cfg.Tracing.Insecure, "tracing.insecure", false, "Export spans over plain HTTP instead of HTTPS.")
+	flag.Float64Var(&cfg.Tracing.SamplingRatio, "tracing.sampling-ratio", 0.1, "The ratio of traces sampled, between 0 and 1, unless the caller already decided to sample them.") //nolint:lll,gomnd
+	flag.StringVar(&cfg.Tracing.ServiceName, "tracing.service-name", "opa-openshift", "The service name attached to exported spans.")
 
 	// Integration testing flags
 	flag.StringVar(&cfg.DebugToken, "debug.token", "", "Debug bearer token used for integration tests.")
@@ -154,7 +350,46 @@ func ParseFlags() (*Config, error) {
 		return nil, fmt.Errorf("%w: %s", errInvalidOPARule, cfg.Opa.Rule)
 	}
 
-	if *mappingsRaw == nil {
+	if cfg.OpenShift.SARConcurrency < 1 {
+		return nil, fmt.Errorf("%w: %d", errInvalidConcurrency, cfg.OpenShift.SARConcurrency)
+	}
+
+	if cfg.OpenShift.ClientPoolSize < 1 {
+		return nil, fmt.Errorf("%w: %d", errInvalidPoolSize, cfg.OpenShift.ClientPoolSize)
+	}
+
+	if len(cfg.Memcached.Servers) > 0 && len(cfg.Redis.Addrs) > 0 {
+		return nil, errConflictingCaches
+	}
+
+	// With a master name, the addresses are Sentinels and a Cluster cannot be reached.
+	if cfg.Redis.MasterName != "" && cfg.Redis.Cluster {
+		return nil, errConflictingRedis
+	}
+
+	if cfg.Server.AdminTokenFile != "" && cfg.Cache.SubjectKeyFile == "" {
+		return nil, errMissingSubjectKey
+	}
+
+	// SelfSubjectRulesReviews return the rules of the holder of the token, so they
+	// must not be mixed with SubjectAccessReviews of the subject of the request.
+	if cfg.Opa.SSRR && !cfg.Opa.SSAR {
+		return nil, fmt.Errorf("%w: --opa.ssrr requires --opa.ssar", errInvalidRulesReview)
+	}
+
+	if err := validateAudit(&cfg.Audit); err != nil {
+		return nil, err
+	}
+
+	if err := validateDecisionLogs(&cfg.DecisionLogs); err != nil {
+		return nil, err
+	}
+
+	if cfg.Tracing.SamplingRatio < 0 || cfg.Tracing.SamplingRatio > 1 {
+		return nil, fmt.Errorf("%w: %v", errInvalidTraceRatio, cfg.Tracing.SamplingRatio)
+	}
+
+	if *mappingsRaw == nil && cfg.ConfigFile == "" {
 		stdlog.Fatal("missing tenant mappings")
 	}
 
@@ -169,15 +404,78 @@ func ParseFlags() (*Config, error) {
 		cfg.Mappings[parts[0]] = parts[1]
 	}
 
-	if cfg.Opa.ViaQToOTELMigration {
-		if !strings.Contains(cfg.Opa.Matcher, "kubernetes_namespace_name") || !strings.Contains(cfg.Opa.Matcher, "k8s_namespace_name") {
-			return nil, errViaQOTELMatcher
+	cfg.flagMappings = maps.Clone(cfg.Mappings)
+	cfg.flags = flag.CommandLine
+
+	if cfg.ConfigFile != "" {
+		f, err := LoadFile(cfg.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := cfg.applyFile(f, cfg.flags); err != nil {
+			return nil, err
 		}
 	}
 
+	if err := cfg.Matcher().validate(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
+func validateAudit(cfg *AuditConfig) error {
+	switch cfg.Sink {
+	case "", AuditSinkStderr:
+	case AuditSinkFile:
+		if cfg.FilePath == "" {
+			return fmt.Errorf("%w: --audit.file.path is required for the file sink", errInvalidAuditSink)
+		}
+	case AuditSinkWebhook:
+		if cfg.WebhookURL == "" {
+			return fmt.Errorf("%w: --audit.webhook.url is required for the webhook sink", errInvalidAuditSink)
+		}
+
+		if cfg.WebhookQueueSize < 1 {
+			return fmt.Errorf("%w: --audit.webhook.queue-size must be positive, got %d", errInvalidAuditSink, cfg.WebhookQueueSize)
+		}
+	default:
+		return fmt.Errorf("%w: %s", errInvalidAuditSink, cfg.Sink)
+	}
+
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		return fmt.Errorf("%w: %v", errInvalidSampleRate, cfg.SampleRate)
+	}
+
+	return nil
+}
+
+func validateDecisionLogs(cfg *DecisionLogsConfig) error {
+	if cfg.URL == "" {
+		return nil
+	}
+
+	switch {
+	case cfg.BufferSize < 1:
+		return fmt.Errorf("%w: --decision-logs.buffer-size must be positive, got %d", errInvalidDecisionLog, cfg.BufferSize)
+	case cfg.BatchSize < 1:
+		return fmt.Errorf("%w: --decision-logs.batch-size must be positive, got %d", errInvalidDecisionLog, cfg.BatchSize)
+	case cfg.FlushInterval <= 0:
+		return fmt.Errorf("%w: --decision-logs.flush-interval must be positive, got %s", errInvalidDecisionLog, cfg.FlushInterval)
+	case cfg.Timeout <= 0:
+		return fmt.Errorf("%w: --decision-logs.timeout must be positive, got %s", errInvalidDecisionLog, cfg.Timeout)
+	case cfg.MaxRetries < 0:
+		return fmt.Errorf("%w: --decision-logs.max-retries must not be negative, got %d", errInvalidDecisionLog, cfg.MaxRetries)
+	case cfg.RetryBackoff < 0:
+		return fmt.Errorf("%w: --decision-logs.retry-backoff must not be negative, got %s", errInvalidDecisionLog, cfg.RetryBackoff)
+	case cfg.MaxSpoolFiles < 0:
+		return fmt.Errorf("%w: --decision-logs.spool-max-files must not be negative, got %d", errInvalidDecisionLog, cfg.MaxSpoolFiles)
+	}
+
+	return nil
+}
+
 func parseLogLevel(logLevelRaw *string) (level.Option, error) {
 	switch *logLevelRaw {
 	case "error":
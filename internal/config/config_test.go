@@ -0,0 +1,166 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDecisionLogs(t *testing.T) {
+	valid := DecisionLogsConfig{
+		URL:           "http://collector/logs",
+		BufferSize:    10000,
+		BatchSize:     100,
+		FlushInterval: 5 * time.Second,
+		Timeout:       10 * time.Second,
+		MaxRetries:    3,
+		RetryBackoff:  time.Second,
+		MaxSpoolFiles: 1000,
+	}
+
+	tt := []struct {
+		desc    string
+		modify  func(c *DecisionLogsConfig)
+		wantErr string
+	}{
+		{
+			desc:   "valid",
+			modify: func(*DecisionLogsConfig) {},
+		},
+		{
+			desc:   "disabled",
+			modify: func(c *DecisionLogsConfig) { *c = DecisionLogsConfig{} },
+		},
+		{
+			desc:    "zero buffer size",
+			modify:  func(c *DecisionLogsConfig) { c.BufferSize = 0 },
+			wantErr: "--decision-logs.buffer-size must be positive, got 0",
+		},
+		{
+			desc:    "negative buffer size",
+			modify:  func(c *DecisionLogsConfig) { c.BufferSize = -1 },
+			wantErr: "--decision-logs.buffer-size must be positive, got -1",
+		},
+		{
+			desc:    "zero batch size",
+			modify:  func(c *DecisionLogsConfig) { c.BatchSize = 0 },
+			wantErr: "--decision-logs.batch-size must be positive, got 0",
+		},
+		{
+			desc:    "negative batch size",
+			modify:  func(c *DecisionLogsConfig) { c.BatchSize = -5 },
+			wantErr: "--decision-logs.batch-size must be positive, got -5",
+		},
+		{
+			desc:    "zero flush interval",
+			modify:  func(c *DecisionLogsConfig) { c.FlushInterval = 0 },
+			wantErr: "--decision-logs.flush-interval must be positive, got 0s",
+		},
+		{
+			desc:    "negative flush interval",
+			modify:  func(c *DecisionLogsConfig) { c.FlushInterval = -time.Second },
+			wantErr: "--decision-logs.flush-interval must be positive, got -1s",
+		},
+		{
+			desc:    "zero timeout",
+			modify:  func(c *DecisionLogsConfig) { c.Timeout = 0 },
+			wantErr: "--decision-logs.timeout must be positive, got 0s",
+		},
+		{
+			desc:    "negative retries",
+			modify:  func(c *DecisionLogsConfig) { c.MaxRetries = -1 },
+			wantErr: "--decision-logs.max-retries must not be negative, got -1",
+		},
+		{
+			desc:    "negative retry backoff",
+			modify:  func(c *DecisionLogsConfig) { c.RetryBackoff = -time.Second },
+			wantErr: "--decision-logs.retry-backoff must not be negative, got -1s",
+		},
+		{
+			desc:    "negative spool files",
+			modify:  func(c *DecisionLogsConfig) { c.MaxSpoolFiles = -1 },
+			wantErr: "--decision-logs.spool-max-files must not be negative, got -1",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			c := valid
+			tc.modify(&c)
+
+			err := validateDecisionLogs(&c)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorIs(t, err, errInvalidDecisionLog)
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestValidateAudit(t *testing.T) {
+	valid := AuditConfig{
+		Sink:             AuditSinkWebhook,
+		WebhookURL:       "http://collector/audit",
+		WebhookQueueSize: 1000,
+		SampleRate:       1,
+	}
+
+	tt := []struct {
+		desc    string
+		modify  func(c *AuditConfig)
+		wantErr string
+	}{
+		{
+			desc:   "valid",
+			modify: func(*AuditConfig) {},
+		},
+		{
+			desc:    "missing webhook url",
+			modify:  func(c *AuditConfig) { c.WebhookURL = "" },
+			wantErr: "--audit.webhook.url is required for the webhook sink",
+		},
+		{
+			desc:    "zero queue size",
+			modify:  func(c *AuditConfig) { c.WebhookQueueSize = 0 },
+			wantErr: "--audit.webhook.queue-size must be positive, got 0",
+		},
+		{
+			desc:    "negative queue size",
+			modify:  func(c *AuditConfig) { c.WebhookQueueSize = -1 },
+			wantErr: "--audit.webhook.queue-size must be positive, got -1",
+		},
+		{
+			desc: "queue size ignored without webhook sink",
+			modify: func(c *AuditConfig) {
+				c.Sink = AuditSinkStderr
+				c.WebhookQueueSize = 0
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			c := valid
+			tc.modify(&c)
+
+			err := validateAudit(&c)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorIs(t, err, errInvalidAuditSink)
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// FileVersion is the version of the configuration file schema.
+const FileVersion = "v1"
+
+const (
+	accessReviewSAR  = "sar"
+	accessReviewSSAR = "ssar"
+)
+
+var errInvalidConfigFile = errors.New("invalid config file")
+
+// File is the configuration file given with --config.file, in YAML or JSON.
+type File struct {
+	// Version is the version of the schema, currently "v1".
+	Version string       `json:"version"`
+	Tenants []FileTenant `json:"tenants"`
+}
+
+// FileTenant holds the settings of a single tenant.
+type FileTenant struct {
+	Name string `json:"name"`
+	// APIGroup is the API group access reviews are issued against.
+	APIGroup string `json:"apiGroup"`
+	// Resources maps the resources of requests to the resources checked by access reviews.
+	// Resources missing from the map are checked as is.
+	Resources map[string]string `json:"resources,omitempty"`
+	Matcher   *FileMatcher      `json:"matcher,omitempty"`
+	// AccessReview is either "sar" for SubjectAccessReviews or "ssar" for SelfSubjectAccessReviews.
+	AccessReview string `json:"accessReview,omitempty"`
+	// RulesReview resolves namespaced access with SelfSubjectRulesReviews. As these return the
+	// rules of the holder of the token, it requires SelfSubjectAccessReviews.
+	RulesReview *bool      `json:"rulesReview,omitempty"`
+	Cache       *FileCache `json:"cache,omitempty"`
+}
+
+// FileMatcher configures the label matcher returned for a tenant.
+type FileMatcher struct {
+	Keys        []string `json:"keys,omitempty"`
+	Op          string   `json:"op,omitempty"`
+	AdminGroups []string `json:"adminGroups,omitempty"`
+	// LabelAliases lists keys naming the same label, see LabelAliases.
+	LabelAliases []LabelAliases `json:"labelAliases,omitempty"`
+	// ViaQToOTELMigration adds the ViaQToOTELAliases preset to the label aliases.
+	ViaQToOTELMigration *bool `json:"viaQToOTELMigration,omitempty"`
+	// Skip omits the label matcher for the tenant.
+	Skip bool `json:"skip,omitempty"`
+}
+
+// FileCache configures the TTLs of the cached decisions of a tenant.
+type FileCache struct {
+	AllowTTL   *metav1.Duration `json:"allowTTL,omitempty"`
+	PartialTTL *metav1.Duration `json:"partialTTL,omitempty"`
+	DenyTTL    *metav1.Duration `json:"denyTTL,omitempty"`
+}
+
+// LoadFile reads and validates a configuration file. Unknown fields are rejected.
+func LoadFile(path string) (*File, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Tenants are decoded one by one, so that unknown fields are reported with their tenant.
+	var raw struct {
+		Version string            `json:"version"`
+		Tenants []json.RawMessage `json:"tenants"`
+	}
+
+	if err := yaml.UnmarshalStrict(b, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidConfigFile, err)
+	}
+
+	f := File{Version: raw.Version, Tenants: make([]FileTenant, len(raw.Tenants))}
+
+	for i, t := range raw.Tenants {
+		dec := json.NewDecoder(bytes.NewReader(t))
+		dec.DisallowUnknownFields()
+
+		if err := dec.Decode(&f.Tenants[i]); err != nil {
+			return nil, invalidField(fmt.Sprintf("tenants[%d]", i), "%v", err)
+		}
+	}
+
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// Validate reports the first invalid field of the configuration file.
+func (f *File) Validate() error {
+	if f.Version != FileVersion {
+		return invalidField("version", "must be %q, got %q", FileVersion, f.Version)
+	}
+
+	names := make(map[string]struct{}, len(f.Tenants))
+
+	for i, t := range f.Tenants {
+		path := fmt.Sprintf("tenants[%d]", i)
+
+		if t.Name == "" {
+			return invalidField(path+".name", "must not be empty")
+		}
+
+		if _, ok := names[t.Name]; ok {
+			return invalidField(path+".name", "duplicate tenant %q", t.Name)
+		}
+
+		names[t.Name] = struct{}{}
+
+		if err := t.validate(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *FileTenant) validate(path string) error {
+	if t.APIGroup == "" {
+		return invalidField(path+".apiGroup", "must not be empty")
+	}
+
+	for _, from := range slices.Sorted(maps.Keys(t.Resources)) {
+		if to := t.Resources[from]; from == "" || to == "" {
+			return invalidField(path+".resources", "must not map %q to %q", from, to)
+		}
+	}
+
+	switch t.AccessReview {
+	case "", accessReviewSAR, accessReviewSSAR:
+	default:
+		return invalidField(path+".accessReview", "must be %q or %q, got %q", accessReviewSAR, accessReviewSSAR, t.AccessReview)
+	}
+
+	if m := t.Matcher; m != nil {
+		for j, k := range m.Keys {
+			if k == "" {
+				return invalidField(fmt.Sprintf("%s.matcher.keys[%d]", path, j), "must not be empty")
+			}
+		}
+
+		for j, a := range m.LabelAliases {
+			if err := a.validate(); err != nil {
+				return invalidField(fmt.Sprintf("%s.matcher.labelAliases[%d]", path, j), "%v", err)
+			}
+		}
+
+		switch MatcherOp(m.Op) {
+		case "":
+			if len(m.Keys) > 1 {
+				return invalidField(path+".matcher.op", "must be set when several keys are given")
+			}
+		case MatcherAnd, MatcherOr:
+		default:
+			return invalidField(path+".matcher.op", "must be %q or %q, got %q", MatcherAnd, MatcherOr, m.Op)
+		}
+	}
+
+	if c := t.Cache; c != nil {
+		for _, f := range []struct {
+			name string
+			ttl  *metav1.Duration
+		}{
+			{"allowTTL", c.AllowTTL},
+			{"partialTTL", c.PartialTTL},
+			{"denyTTL", c.DenyTTL},
+		} {
+			if f.ttl != nil && f.ttl.Duration < 0 {
+				return invalidField(path+".cache."+f.name, "must not be negative, got %s", f.ttl.Duration)
+			}
+		}
+	}
+
+	return nil
+}
+
+func invalidField(field, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s: %s", errInvalidConfigFile, field, fmt.Sprintf(format, args...))
+}
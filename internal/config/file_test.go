@@ -0,0 +1,239 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigFile = `
+version: v1
+tenants:
+- name: application
+  apiGroup: loki.grafana.com
+  resources:
+    logs: application-logs
+  matcher:
+    keys: [kubernetes_namespace_name, k8s_namespace_name]
+    op: or
+    adminGroups: [cluster-admin]
+    viaQToOTELMigration: true
+  accessReview: ssar
+  rulesReview: true
+  cache:
+    allowTTL: 5m
+    denyTTL: 10s
+- name: platform
+  apiGroup: observatorium.openshift.io
+  matcher:
+    skip: true
+`
+
+func TestLoadFile(t *testing.T) {
+	tt := []struct {
+		desc    string
+		content string
+		wantErr string
+	}{
+		{
+			desc:    "valid YAML",
+			content: testConfigFile,
+		},
+		{
+			desc:    "valid JSON",
+			content: `{"version": "v1", "tenants": [{"name": "application", "apiGroup": "loki.grafana.com"}]}`,
+		},
+		{
+			desc:    "missing version",
+			content: `tenants: []`,
+			wantErr: `version: must be "v1", got ""`,
+		},
+		{
+			desc:    "unknown field",
+			content: "version: v1\ntenants:\n- name: a\n  apiGroups: loki.grafana.com\n",
+			wantErr: `tenants[0]: json: unknown field "apiGroups"`,
+		},
+		{
+			desc:    "missing name",
+			content: "version: v1\ntenants:\n- apiGroup: loki.grafana.com\n",
+			wantErr: "tenants[0].name: must not be empty",
+		},
+		{
+			desc:    "duplicate tenant",
+			content: "version: v1\ntenants:\n- name: a\n  apiGroup: g\n- name: a\n  apiGroup: g\n",
+			wantErr: `tenants[1].name: duplicate tenant "a"`,
+		},
+		{
+			desc:    "missing API group",
+			content: "version: v1\ntenants:\n- name: a\n",
+			wantErr: "tenants[0].apiGroup: must not be empty",
+		},
+		{
+			desc:    "invalid access review",
+			content: "version: v1\ntenants:\n- name: a\n  apiGroup: g\n  accessReview: rbac\n",
+			wantErr: `tenants[0].accessReview: must be "sar" or "ssar", got "rbac"`,
+		},
+		{
+			desc:    "invalid matcher op",
+			content: "version: v1\ntenants:\n- name: a\n  apiGroup: g\n  matcher:\n    keys: [a, b]\n    op: xor\n",
+			wantErr: `tenants[0].matcher.op: must be "and" or "or", got "xor"`,
+		},
+		{
+			desc:    "missing matcher op",
+			content: "version: v1\ntenants:\n- name: a\n  apiGroup: g\n  matcher:\n    keys: [a, b]\n",
+			wantErr: "tenants[0].matcher.op: must be set when several keys are given",
+		},
+		{
+			desc:    "invalid label aliases",
+			content: "version: v1\ntenants:\n- name: a\n  apiGroup: g\n  matcher:\n    keys: [a, b]\n    op: or\n    labelAliases: [[a]]\n",
+			wantErr: "tenants[0].matcher.labelAliases[0]: invalid label aliases",
+		},
+		{
+			desc:    "negative TTL",
+			content: "version: v1\ntenants:\n- name: a\n  apiGroup: g\n  cache:\n    denyTTL: -1s\n",
+			wantErr: "tenants[0].cache.denyTTL: must not be negative, got -1s",
+		},
+		{
+			desc:    "invalid TTL",
+			content: "version: v1\ntenants:\n- name: a\n  apiGroup: g\n  cache:\n    denyTTL: soon\n",
+			wantErr: "invalid config file",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			_, err := LoadFile(path)
+			if tc.wantErr != "" {
+				require.ErrorIs(t, err, errInvalidConfigFile)
+				require.ErrorContains(t, err, tc.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestConfigApplyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfigFile), 0o600))
+
+	f, err := LoadFile(path)
+	require.NoError(t, err)
+
+	tt := []struct {
+		desc         string
+		args         []string
+		wantPlatform string
+		wantTenant   Tenant
+		wantKeys     []string
+		wantAliases  []LabelAliases
+	}{
+		{
+			desc:         "file",
+			wantPlatform: "observatorium.openshift.io",
+			wantTenant: Tenant{
+				Name:      "application",
+				APIGroup:  "loki.grafana.com",
+				Resources: map[string]string{"logs": "application-logs"},
+				SSAR:      true,
+				SSRR:      true,
+				AllowTTL:  5 * time.Minute,
+				DenyTTL:   10 * time.Second,
+			},
+			wantKeys:    []string{"kubernetes_namespace_name", "k8s_namespace_name"},
+			wantAliases: []LabelAliases{ViaQToOTELAliases},
+		},
+		{
+			desc:         "flag overrides",
+			args:         []string{"--opa.ssar=false", "--opa.ssrr=false", "--cache.ttl.allow=1m", "--opa.matcher=namespace", "--opa.viaq-to-otel-migration=false", "--openshift.mappings=platform=monitoring.coreos.com"},
+			wantPlatform: "monitoring.coreos.com",
+			wantTenant: Tenant{
+				Name:      "application",
+				APIGroup:  "loki.grafana.com",
+				Resources: map[string]string{"logs": "application-logs"},
+				AllowTTL:  time.Minute,
+				DenyTTL:   10 * time.Second,
+			},
+			wantKeys: []string{"namespace"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			cfg := &Config{}
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.BoolVar(&cfg.Opa.SSAR, "opa.ssar", false, "")
+			fs.BoolVar(&cfg.Opa.SSRR, "opa.ssrr", false, "")
+			fs.StringVar(&cfg.Opa.Matcher, "opa.matcher", "", "")
+			fs.BoolVar(&cfg.Opa.ViaQToOTELMigration, "opa.viaq-to-otel-migration", false, "")
+			fs.DurationVar(&cfg.Cache.AllowTTL, "cache.ttl.allow", 0, "")
+			mappings := fs.StringToString("openshift.mappings", nil, "")
+			require.NoError(t, fs.Parse(tc.args))
+
+			cfg.Mappings = *mappings
+			require.NoError(t, cfg.applyFile(f, fs))
+
+			got, ok := cfg.Tenant("application")
+			require.True(t, ok)
+			require.Equal(t, tc.wantTenant, got)
+
+			m := cfg.Matcher().ForTenant("application")
+			require.Equal(t, tc.wantKeys, m.Keys)
+			require.Equal(t, MatcherOr, m.MatcherOp)
+			require.Equal(t, tc.wantAliases, m.LabelAliases())
+			require.True(t, cfg.Matcher().ForRequest("application", []string{"cluster-admin"}).IsEmpty())
+
+			platform, ok := cfg.Tenant("platform")
+			require.True(t, ok)
+			require.Equal(t, tc.wantPlatform, platform.APIGroup)
+			require.Equal(t, tc.wantPlatform, cfg.Mappings["platform"])
+			require.True(t, cfg.Matcher().ForRequest("platform", nil).IsEmpty())
+		})
+	}
+}
+
+func TestConfigApplyFile_InvalidMatcher(t *testing.T) {
+	f := &File{
+		Version: FileVersion,
+		Tenants: []FileTenant{{
+			Name:     "application",
+			APIGroup: "loki.grafana.com",
+			Matcher:  &FileMatcher{Keys: []string{"namespace"}, LabelAliases: []LabelAliases{{"namespace", "k8s_namespace_name"}}},
+		}},
+	}
+
+	cfg := &Config{}
+
+	err := cfg.applyFile(f, flag.NewFlagSet("test", flag.ContinueOnError))
+	require.ErrorIs(t, err, errInvalidLabelAliases)
+	require.ErrorContains(t, err, `tenant "application"`)
+}
+
+func TestConfigApplyFile_InvalidRulesReview(t *testing.T) {
+	rulesReview := true
+	f := &File{
+		Version: FileVersion,
+		Tenants: []FileTenant{{
+			Name:         "application",
+			APIGroup:     "loki.grafana.com",
+			AccessReview: accessReviewSAR,
+			RulesReview:  &rulesReview,
+		}},
+	}
+
+	cfg := &Config{}
+
+	err := cfg.applyFile(f, flag.NewFlagSet("test", flag.ContinueOnError))
+	require.ErrorIs(t, err, errInvalidRulesReview)
+	require.ErrorContains(t, err, `tenant "application"`)
+}
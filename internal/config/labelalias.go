@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var errInvalidLabelAliases = errors.New("invalid label aliases")
+
+// LabelAliases lists label keys naming the same label, e.g. the names of a label
+// before and after a migration. A matcher holding all of them only returns the
+// alias selected by a request, or the first one when the request selects none.
+type LabelAliases []string
+
+// ViaQToOTELAliases is the preset enabled by --opa.viaq-to-otel-migration for logs
+// labelled with either the ViaQ or the OpenTelemetry namespace label.
+var ViaQToOTELAliases = LabelAliases{"kubernetes_namespace_name", "k8s_namespace_name"}
+
+// LabelAliases returns the label aliases of the matcher, including the ViaQ to
+// OTel preset when the migration is enabled.
+func (m *Matcher) LabelAliases() []LabelAliases {
+	isViaQ := func(a LabelAliases) bool { return slices.Equal(a, ViaQToOTELAliases) }
+
+	if !m.viaQToOTEL || slices.ContainsFunc(m.aliases, isViaQ) {
+		return m.aliases
+	}
+
+	return append(slices.Clone(m.aliases), ViaQToOTELAliases)
+}
+
+// addViaQToOTELKeys adds the keys of the ViaQ to OTel preset missing from the matcher
+// when the migration is enabled, so that --opa.viaq-to-otel-migration keeps accepting
+// matchers naming only one of them. An empty matcher is left empty.
+func (m *Matcher) addViaQToOTELKeys() {
+	if !m.viaQToOTEL || len(m.Keys) == 0 {
+		return
+	}
+
+	for _, k := range ViaQToOTELAliases {
+		if !slices.Contains(m.Keys, k) {
+			m.Keys = append(m.Keys, k)
+		}
+	}
+}
+
+// MixedAliases returns the first two aliases of a label that are both selected by
+// the given selectors. Such requests cannot be restricted by a single label key.
+func (m *Matcher) MixedAliases(selectors map[string][]string) (string, string, bool) {
+	for _, aliases := range m.LabelAliases() {
+		var selected []string
+
+		for _, a := range aliases {
+			if len(selectors[a]) > 0 {
+				selected = append(selected, a)
+			}
+		}
+
+		if len(selected) > 1 {
+			return selected[0], selected[1], true
+		}
+	}
+
+	return "", "", false
+}
+
+// SelectAliases drops from the keys every alias of a label but the one selected by
+// the given selectors, or the first alias when the selectors select none of them.
+func (m *Matcher) SelectAliases(selectors map[string][]string) {
+	for _, aliases := range m.LabelAliases() {
+		keep := aliases[0]
+
+		for _, a := range aliases {
+			if len(selectors[a]) > 0 {
+				keep = a
+
+				break
+			}
+		}
+
+		m.Keys = slices.DeleteFunc(m.Keys, func(k string) bool {
+			return k != keep && slices.Contains(aliases, k)
+		})
+	}
+}
+
+// validate checks that every label alias names at least two keys held by the
+// matcher, and that no key is the alias of several labels.
+func (m *Matcher) validate() error {
+	seen := map[string]struct{}{}
+
+	for _, aliases := range m.LabelAliases() {
+		if err := aliases.validate(); err != nil {
+			return err
+		}
+
+		for _, a := range aliases {
+			if !slices.Contains(m.Keys, a) {
+				return fmt.Errorf("%w: OPA matcher must contain every alias of %q, missing %q", errInvalidLabelAliases, aliases, a)
+			}
+
+			if _, ok := seen[a]; ok {
+				return fmt.Errorf("%w: %q is the alias of several labels", errInvalidLabelAliases, a)
+			}
+
+			seen[a] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+func (a LabelAliases) validate() error {
+	if len(a) < 2 { //nolint:gomnd
+		return fmt.Errorf("%w: %q must name at least two keys", errInvalidLabelAliases, a)
+	}
+
+	if slices.Contains(a, "") {
+		return fmt.Errorf("%w: %q must not contain empty keys", errInvalidLabelAliases, a)
+	}
+
+	return nil
+}
+
+// toLabelAliases parses label aliases given as comma-separated keys.
+func toLabelAliases(groups []string) []LabelAliases {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	aliases := make([]LabelAliases, 0, len(groups))
+	for _, g := range groups {
+		aliases = append(aliases, strings.Split(g, matchersSeparator))
+	}
+
+	return aliases
+}
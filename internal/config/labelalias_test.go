@@ -0,0 +1,179 @@
+package config
+
+import (
+	"testing"
+
+	flag "github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcherSelectAliases(t *testing.T) {
+	tt := []struct {
+		desc      string
+		opaConfig OPAConfig
+		selectors map[string][]string
+		wantKeys  []string
+		wantMixed []string
+	}{
+		{
+			desc:      "ViaQ preset without selectors",
+			opaConfig: OPAConfig{Matcher: "kubernetes_namespace_name,k8s_namespace_name", ViaQToOTELMigration: true},
+			wantKeys:  []string{"kubernetes_namespace_name"},
+		},
+		{
+			desc:      "ViaQ preset with ViaQ selectors",
+			opaConfig: OPAConfig{Matcher: "kubernetes_namespace_name,k8s_namespace_name", ViaQToOTELMigration: true},
+			selectors: map[string][]string{"kubernetes_namespace_name": {"ns-a"}},
+			wantKeys:  []string{"kubernetes_namespace_name"},
+		},
+		{
+			desc:      "ViaQ preset with OTel selectors",
+			opaConfig: OPAConfig{Matcher: "kubernetes_namespace_name,k8s_namespace_name", ViaQToOTELMigration: true},
+			selectors: map[string][]string{"k8s_namespace_name": {"ns-a"}},
+			wantKeys:  []string{"k8s_namespace_name"},
+		},
+		{
+			desc:      "ViaQ preset with mixed selectors",
+			opaConfig: OPAConfig{Matcher: "kubernetes_namespace_name,k8s_namespace_name", ViaQToOTELMigration: true},
+			selectors: map[string][]string{"kubernetes_namespace_name": {"ns-a"}, "k8s_namespace_name": {"ns-b"}},
+			wantMixed: []string{"kubernetes_namespace_name", "k8s_namespace_name"},
+		},
+		{
+			desc:      "ViaQ preset with empty selector",
+			opaConfig: OPAConfig{Matcher: "kubernetes_namespace_name,k8s_namespace_name", ViaQToOTELMigration: true},
+			selectors: map[string][]string{"kubernetes_namespace_name": {"ns-a"}, "k8s_namespace_name": {}},
+			wantKeys:  []string{"kubernetes_namespace_name"},
+		},
+		{
+			desc:      "no aliases",
+			opaConfig: OPAConfig{Matcher: "kubernetes_namespace_name,k8s_namespace_name", MatcherOp: string(MatcherOr)},
+			selectors: map[string][]string{"kubernetes_namespace_name": {"ns-a"}, "k8s_namespace_name": {"ns-b"}},
+			wantKeys:  []string{"kubernetes_namespace_name", "k8s_namespace_name"},
+		},
+		{
+			desc:      "aliases with other keys",
+			opaConfig: OPAConfig{Matcher: "namespace,cluster,k8s_namespace_name", MatcherOp: string(MatcherAnd), LabelAliases: []string{"namespace,k8s_namespace_name"}},
+			selectors: map[string][]string{"k8s_namespace_name": {"ns-a"}, "cluster": {"a"}},
+			wantKeys:  []string{"cluster", "k8s_namespace_name"},
+		},
+		{
+			desc:      "several aliases",
+			opaConfig: OPAConfig{Matcher: "a1,a2,a3,b1,b2", MatcherOp: string(MatcherAnd), LabelAliases: []string{"a1,a2,a3", "b1,b2"}},
+			selectors: map[string][]string{"a3": {"ns-a"}},
+			wantKeys:  []string{"a3", "b1"},
+		},
+		{
+			desc:      "several aliases with mixed selectors",
+			opaConfig: OPAConfig{Matcher: "a1,a2,a3,b1,b2", MatcherOp: string(MatcherAnd), LabelAliases: []string{"a1,a2,a3", "b1,b2"}},
+			selectors: map[string][]string{"a1": {"ns-a"}, "b2": {"ns-a"}, "a3": {"ns-b"}},
+			wantMixed: []string{"a1", "a3"},
+		},
+		{
+			desc:      "ViaQ preset and aliases",
+			opaConfig: OPAConfig{Matcher: "kubernetes_namespace_name,k8s_namespace_name,namespace", MatcherOp: string(MatcherOr), ViaQToOTELMigration: true, LabelAliases: []string{"namespace,k8s_namespace_name"}},
+			selectors: map[string][]string{"k8s_namespace_name": {"ns-a"}},
+			wantKeys:  []string{"k8s_namespace_name"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			m := tc.opaConfig.ToMatcher()
+
+			a, b, mixed := m.MixedAliases(tc.selectors)
+			if tc.wantMixed != nil {
+				require.True(t, mixed)
+				require.Equal(t, tc.wantMixed, []string{a, b})
+
+				return
+			}
+
+			require.False(t, mixed)
+
+			m.SelectAliases(tc.selectors)
+			require.Equal(t, tc.wantKeys, m.Keys)
+		})
+	}
+}
+
+func TestMatcherValidate(t *testing.T) {
+	tt := []struct {
+		desc      string
+		opaConfig OPAConfig
+		wantErr   string
+	}{
+		{
+			desc:      "no aliases",
+			opaConfig: OPAConfig{Matcher: "namespace"},
+		},
+		{
+			desc:      "ViaQ preset",
+			opaConfig: OPAConfig{Matcher: "kubernetes_namespace_name,k8s_namespace_name", ViaQToOTELMigration: true},
+		},
+		{
+			desc:      "ViaQ preset without OTel key",
+			opaConfig: OPAConfig{Matcher: "kubernetes_namespace_name", ViaQToOTELMigration: true},
+		},
+		{
+			desc:      "ViaQ preset without matcher",
+			opaConfig: OPAConfig{ViaQToOTELMigration: true},
+			wantErr:   `missing "kubernetes_namespace_name"`,
+		},
+		{
+			desc:      "alias without key",
+			opaConfig: OPAConfig{Matcher: "namespace", LabelAliases: []string{"namespace,k8s_namespace_name"}},
+			wantErr:   `missing "k8s_namespace_name"`,
+		},
+		{
+			desc:      "single key",
+			opaConfig: OPAConfig{Matcher: "namespace", LabelAliases: []string{"namespace"}},
+			wantErr:   "must name at least two keys",
+		},
+		{
+			desc:      "empty key",
+			opaConfig: OPAConfig{Matcher: "namespace", LabelAliases: []string{"namespace,"}},
+			wantErr:   "must not contain empty keys",
+		},
+		{
+			desc:      "key in several aliases",
+			opaConfig: OPAConfig{Matcher: "a,b,c", LabelAliases: []string{"a,b", "b,c"}},
+			wantErr:   `"b" is the alias of several labels`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			m := tc.opaConfig.ToMatcher()
+
+			err := m.validate()
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorIs(t, err, errInvalidLabelAliases)
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestMatcher_ViaQToOTELKeys(t *testing.T) {
+	// The preset keeps accepting matchers naming only one of its keys.
+	opa := OPAConfig{Matcher: "kubernetes_namespace_name", ViaQToOTELMigration: true}
+	m := opa.ToMatcher()
+	require.Equal(t, []string{"kubernetes_namespace_name", "k8s_namespace_name"}, m.Keys)
+	require.NoError(t, m.validate())
+
+	opa.Matcher = "namespace,k8s_namespace_name"
+	m = opa.ToMatcher()
+	require.Equal(t, []string{"namespace", "k8s_namespace_name", "kubernetes_namespace_name"}, m.Keys)
+
+	cfg := &Config{Opa: opa}
+	m = *cfg.tenantMatcher("application", &FileMatcher{Keys: []string{"k8s_namespace_name"}}, flag.NewFlagSet("test", flag.ContinueOnError))
+	require.Equal(t, []string{"k8s_namespace_name", "kubernetes_namespace_name"}, m.Keys)
+}
@@ -19,6 +19,10 @@ type Matcher struct {
 	MatcherOp   MatcherOp
 	skipTenants map[string]struct{}
 	adminGroups map[string]struct{}
+	aliases     []LabelAliases
+	viaQToOTEL  bool
+	// tenants holds the matchers of the tenants configured with their own settings.
+	tenants map[string]*Matcher
 }
 
 func (m *Matcher) Clone() *Matcher {
@@ -27,6 +31,8 @@ func (m *Matcher) Clone() *Matcher {
 		MatcherOp:   m.MatcherOp,
 		skipTenants: maps.Clone(m.skipTenants),
 		adminGroups: maps.Clone(m.adminGroups),
+		aliases:     slices.Clone(m.aliases),
+		viaQToOTEL:  m.viaQToOTEL,
 	}
 }
 
@@ -40,12 +46,16 @@ func (c *OPAConfig) ToMatcher() Matcher {
 		MatcherOp:   matcherOp,
 		skipTenants: skipTenants,
 		adminGroups: adminGroups,
+		aliases:     toLabelAliases(c.LabelAliases),
+		viaQToOTEL:  c.ViaQToOTELMigration,
 	}
 
 	if keys := strings.Split(matcherKeys, matchersSeparator); len(keys) > 0 && keys[0] != "" {
 		matcher.Keys = keys
 	}
 
+	matcher.addViaQToOTELKeys()
+
 	return matcher
 }
 
@@ -80,7 +90,22 @@ func EmptyMatcher() *Matcher {
 	return &Matcher{}
 }
 
+// ForTenant returns the matcher configured for the given tenant, or the matcher
+// itself when the tenant has no settings of its own.
+func (m *Matcher) ForTenant(tenant string) *Matcher {
+	if tm, ok := m.tenants[tenant]; ok {
+		return tm
+	}
+
+	return m
+}
+
+// ForRequest returns the matcher of the given tenant to apply to a request of a
+// subject with the given groups. The matcher is empty for skipped tenants and
+// admin groups.
 func (m *Matcher) ForRequest(tenant string, groups []string) *Matcher {
+	m = m.ForTenant(tenant)
+
 	if m.IsEmpty() {
 		return m
 	}
@@ -97,18 +122,3 @@ func (m *Matcher) ForRequest(tenant string, groups []string) *Matcher {
 
 	return m.Clone() // Return a clone for request-specific modifications
 }
-
-func (m *Matcher) ViaQToOTELMigration(selectors map[string][]string) {
-	if vals, ok := selectors["k8s_namespace_name"]; ok && len(vals) > 0 {
-		if i := slices.Index(m.Keys, "kubernetes_namespace_name"); i != -1 {
-			m.Keys = slices.Delete(m.Keys, i, i+1)
-		}
-		return
-	}
-	// Here we always delete the key "k8s_namespace_name" from the keys
-	// to cover both the cases where kubernetes_namespace_name is present or no
-	// selectors were present
-	if i := slices.Index(m.Keys, "k8s_namespace_name"); i != -1 {
-		m.Keys = slices.Delete(m.Keys, i, i+1)
-	}
-}
@@ -91,3 +91,58 @@ func TestMatcherForRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestMatcherForTenant(t *testing.T) {
+	global := (&OPAConfig{
+		Matcher:             "kubernetes_namespace_name,k8s_namespace_name",
+		MatcherOp:           string(MatcherOr),
+		MatcherAdminGroups:  "admin-group",
+		ViaQToOTELMigration: true,
+	}).ToMatcher()
+	global.tenants = map[string]*Matcher{
+		"metrics": {
+			Keys:        []string{"namespace"},
+			adminGroups: map[string]struct{}{"metrics-admin": {}},
+		},
+	}
+
+	tt := []struct {
+		desc        string
+		tenant      string
+		groups      []string
+		wantMatcher []string
+		wantAliases []LabelAliases
+	}{
+		{
+			desc:        "tenant without own settings",
+			tenant:      "application",
+			wantMatcher: []string{"kubernetes_namespace_name", "k8s_namespace_name"},
+			wantAliases: []LabelAliases{ViaQToOTELAliases},
+		},
+		{
+			desc:        "tenant with own settings",
+			tenant:      "metrics",
+			wantMatcher: []string{"namespace"},
+		},
+		{
+			desc:        "global admin group on tenant with own settings",
+			tenant:      "metrics",
+			groups:      []string{"admin-group"},
+			wantMatcher: []string{"namespace"},
+		},
+		{
+			desc:   "tenant admin group",
+			tenant: "metrics",
+			groups: []string{"metrics-admin"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.wantAliases, global.ForTenant(tc.tenant).LabelAliases())
+			require.Equal(t, tc.wantMatcher, global.ForRequest(tc.tenant, tc.groups).Keys)
+		})
+	}
+}
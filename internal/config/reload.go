@@ -0,0 +1,196 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// reloadDebounce delays reloads after a file change, so that files written in
+// several steps or swapped by Kubernetes are only reloaded once.
+const reloadDebounce = 100 * time.Millisecond
+
+// Reloader reloads the configuration file and the server certificates when their
+// files change or the process receives SIGHUP. Invalid files are rejected while
+// the previous configuration and certificates are kept.
+type Reloader struct {
+	logger   log.Logger
+	keyPairs []*KeyPair
+	debounce time.Duration
+
+	mu     sync.Mutex
+	config atomic.Pointer[Config]
+
+	lastSuccess          prometheus.Gauge
+	lastSuccessTimestamp prometheus.Gauge
+}
+
+// NewReloader returns a Reloader starting from the given configuration and
+// reloading the given key pairs; nil key pairs are ignored.
+func NewReloader(l log.Logger, cfg *Config, r prometheus.Registerer, keyPairs ...*KeyPair) *Reloader {
+	rl := &Reloader{
+		logger:   l,
+		debounce: reloadDebounce,
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "opa_openshift_config_last_reload_success",
+			Help: "Whether the last reload of the configuration file and the certificates succeeded.",
+		}),
+		lastSuccessTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "opa_openshift_config_last_reload_success_timestamp_seconds",
+			Help: "Timestamp of the last successful reload of the configuration file and the certificates.",
+		}),
+	}
+
+	for _, kp := range keyPairs {
+		if kp != nil {
+			rl.keyPairs = append(rl.keyPairs, kp)
+		}
+	}
+
+	rl.config.Store(cfg)
+	rl.lastSuccess.Set(1)
+	rl.lastSuccessTimestamp.SetToCurrentTime()
+
+	if r != nil {
+		r.MustRegister(rl.lastSuccess, rl.lastSuccessTimestamp)
+	}
+
+	return rl
+}
+
+// Config returns the current configuration.
+func (r *Reloader) Config() *Config {
+	return r.config.Load()
+}
+
+// Reload reloads the configuration file and the certificates. Every file failing
+// to load keeps its previous version in use.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+
+	next, err := r.Config().Reload()
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		r.config.Store(next)
+	}
+
+	for _, kp := range r.keyPairs {
+		if err := kp.Reload(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		r.lastSuccess.Set(0)
+		return err
+	}
+
+	r.lastSuccess.Set(1)
+	r.lastSuccessTimestamp.SetToCurrentTime()
+
+	return nil
+}
+
+// Run reloads on SIGHUP and on changes to the directories of the configuration
+// file and the certificates until the context is done. Directories are watched
+// instead of files, so that files swapped by Kubernetes volume updates are seen.
+func (r *Reloader) Run(ctx context.Context) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	defer func() { _ = w.Close() }()
+
+	for _, dir := range r.dirs() {
+		if err := w.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	var debounce <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			r.reload("signal")
+		case _, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+
+			debounce = time.After(r.debounce)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+
+			level.Warn(r.logger).Log("msg", "failed to watch configuration files", "err", err) //nolint:errcheck
+		case <-debounce:
+			debounce = nil
+
+			r.reload("file change")
+		}
+	}
+}
+
+func (r *Reloader) reload(trigger string) {
+	if err := r.Reload(); err != nil {
+		level.Error(r.logger).Log("msg", "failed to reload configuration, keeping the previous one", "trigger", trigger, "err", err) //nolint:errcheck,lll
+		return
+	}
+
+	level.Info(r.logger).Log("msg", "reloaded configuration", "trigger", trigger) //nolint:errcheck
+}
+
+// dirs returns the directories holding the configuration file and the certificates.
+func (r *Reloader) dirs() []string {
+	var files []string
+
+	if f := r.Config().ConfigFile; f != "" {
+		files = append(files, f)
+	}
+
+	for _, kp := range r.keyPairs {
+		files = append(files, kp.Files()...)
+	}
+
+	seen := map[string]struct{}{}
+
+	var dirs []string
+
+	for _, f := range files {
+		dir := filepath.Dir(f)
+		if _, ok := seen[dir]; ok {
+			continue
+		}
+
+		seen[dir] = struct{}{}
+		dirs = append(dirs, dir)
+	}
+
+	return dirs
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTenants(t *testing.T, path string, tenants ...string) {
+	t.Helper()
+
+	content := "version: v1\ntenants:\n"
+	for _, name := range tenants {
+		content += "- name: " + name + "\n  apiGroup: " + name + ".observatorium.io\n"
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestReloader_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeTenants(t, path, "a")
+
+	cfg, err := (&Config{ConfigFile: path, Mappings: map[string]string{"static": "static.io"}}).Reload()
+	require.NoError(t, err)
+
+	reg := prometheus.NewRegistry()
+	r := NewReloader(log.NewNopLogger(), cfg, reg)
+
+	writeTenants(t, path, "a", "b")
+	require.NoError(t, r.Reload())
+
+	_, ok := r.Config().Tenant("b")
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"a": "a.observatorium.io", "b": "b.observatorium.io"}, r.Config().Mappings)
+
+	require.NoError(t, testutil.CollectAndCompare(reg, strings.NewReader(`
+# HELP opa_openshift_config_last_reload_success Whether the last reload of the configuration file and the certificates succeeded.
+# TYPE opa_openshift_config_last_reload_success gauge
+opa_openshift_config_last_reload_success 1
+`), "opa_openshift_config_last_reload_success"))
+
+	// An invalid file is rejected and the previous configuration is kept.
+	require.NoError(t, os.WriteFile(path, []byte("version: v2\n"), 0o600))
+	require.ErrorIs(t, r.Reload(), errInvalidConfigFile)
+
+	_, ok = r.Config().Tenant("b")
+	require.True(t, ok)
+
+	require.NoError(t, testutil.CollectAndCompare(reg, strings.NewReader(`
+# HELP opa_openshift_config_last_reload_success Whether the last reload of the configuration file and the certificates succeeded.
+# TYPE opa_openshift_config_last_reload_success gauge
+opa_openshift_config_last_reload_success 0
+`), "opa_openshift_config_last_reload_success"))
+	require.Equal(t, 1, testutil.CollectAndCount(reg, "opa_openshift_config_last_reload_success_timestamp_seconds"))
+}
+
+func TestReloader_Run(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeTenants(t, path, "a")
+
+	cfg, err := (&Config{ConfigFile: path}).Reload()
+	require.NoError(t, err)
+
+	r := NewReloader(log.NewNopLogger(), cfg, nil)
+	r.debounce = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() { done <- r.Run(ctx) }()
+
+	// Wait for the watcher to be set up by changing the file until the change is seen.
+	require.Eventually(t, func() bool {
+		writeTenants(t, path, "a", "b")
+
+		_, ok := r.Config().Tenant("b")
+
+		return ok
+	}, 5*time.Second, 50*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestKeyPair_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	writeKeyPair(t, certFile, keyFile, "first")
+
+	kp, err := NewKeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	require.Equal(t, "first", commonName(t, kp))
+
+	writeKeyPair(t, certFile, keyFile, "second")
+	require.NoError(t, kp.Reload())
+	require.Equal(t, "second", commonName(t, kp))
+
+	require.NoError(t, os.WriteFile(certFile, []byte("invalid"), 0o600))
+	require.Error(t, kp.Reload())
+	require.Equal(t, "second", commonName(t, kp))
+
+	kp, err = NewKeyPair("", "")
+	require.NoError(t, err)
+	require.Nil(t, kp)
+}
+
+func commonName(t *testing.T, kp *KeyPair) string {
+	t.Helper()
+
+	cert, err := kp.GetCertificate(nil)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	return leaf.Subject.CommonName
+}
+
+// writeKeyPair writes a self-signed certificate with the given common name and its key.
+func writeKeyPair(t *testing.T, certFile, keyFile, cn string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+// Tenant holds the settings of a tenant, resolved from the configuration file
+// and the flags.
+type Tenant struct {
+	Name     string
+	APIGroup string
+	// Resources maps the resources of requests to the resources checked by access reviews.
+	Resources map[string]string
+	SSAR      bool
+	// SSRR resolves namespaced access with SelfSubjectRulesReviews, only set along SSAR.
+	SSRR bool
+
+	AllowTTL   time.Duration
+	PartialTTL time.Duration
+	DenyTTL    time.Duration
+}
+
+// Tenant returns the settings of the given tenant, falling back to the global
+// settings for tenants only given with --openshift.mappings.
+func (c *Config) Tenant(name string) (Tenant, bool) {
+	if t, ok := c.Tenants[name]; ok {
+		return t, true
+	}
+
+	apiGroup, ok := c.Mappings[name]
+	if !ok {
+		return Tenant{}, false
+	}
+
+	return c.defaultTenant(name, apiGroup), true
+}
+
+// Matcher returns the label matcher of the flags, resolving the matchers of the
+// tenants configured in the configuration file with Matcher.ForTenant.
+func (c *Config) Matcher() *Matcher {
+	if c.matcher != nil {
+		return c.matcher
+	}
+
+	m := c.Opa.ToMatcher()
+
+	return &m
+}
+
+// Resource returns the resource checked by access reviews for the given resource of a request.
+func (t *Tenant) Resource(resource string) string {
+	if r, ok := t.Resources[resource]; ok {
+		return r
+	}
+
+	return resource
+}
+
+func (c *Config) defaultTenant(name, apiGroup string) Tenant {
+	return Tenant{
+		Name:       name,
+		APIGroup:   apiGroup,
+		SSAR:       c.Opa.SSAR,
+		SSRR:       c.Opa.SSRR,
+		AllowTTL:   c.Cache.AllowTTL,
+		PartialTTL: c.Cache.PartialTTL,
+		DenyTTL:    c.Cache.DenyTTL,
+	}
+}
+
+// Reload returns a copy of the configuration with the tenants of the configuration
+// file resolved again, leaving the configuration itself untouched. It returns the
+// configuration as is when no configuration file is given.
+func (c *Config) Reload() (*Config, error) {
+	if c.ConfigFile == "" {
+		return c, nil
+	}
+
+	f, err := LoadFile(c.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := c.flags
+	if fs == nil {
+		fs = flag.NewFlagSet("", flag.ContinueOnError)
+	}
+
+	next := *c
+	next.Mappings = maps.Clone(c.flagMappings)
+
+	if err := next.applyFile(f, fs); err != nil {
+		return nil, err
+	}
+
+	return &next, nil
+}
+
+// applyFile resolves the tenants of the configuration file on top of the global
+// settings. Flags given on the command line override the settings of all tenants,
+// and tenants given with --openshift.mappings override the API group of a tenant.
+func (c *Config) applyFile(f *File, fs *flag.FlagSet) error {
+	c.Tenants = make(map[string]Tenant, len(f.Tenants))
+
+	matcher := c.Opa.ToMatcher()
+	matcher.tenants = map[string]*Matcher{}
+
+	if c.Mappings == nil {
+		c.Mappings = make(map[string]string, len(f.Tenants))
+	}
+
+	for _, ft := range f.Tenants {
+		apiGroup := ft.APIGroup
+		if g, ok := c.Mappings[ft.Name]; ok {
+			apiGroup = g
+		}
+
+		c.Mappings[ft.Name] = apiGroup
+
+		t := c.defaultTenant(ft.Name, apiGroup)
+		t.Resources = maps.Clone(ft.Resources)
+
+		if ft.AccessReview != "" && !fs.Changed("opa.ssar") {
+			t.SSAR = ft.AccessReview == accessReviewSSAR
+		}
+
+		if ft.RulesReview != nil && !fs.Changed("opa.ssrr") {
+			t.SSRR = *ft.RulesReview
+		}
+
+		if t.SSRR && !t.SSAR {
+			return fmt.Errorf("tenant %q: %w", ft.Name, errInvalidRulesReview)
+		}
+
+		if fc := ft.Cache; fc != nil {
+			if fc.AllowTTL != nil && !fs.Changed("cache.ttl.allow") {
+				t.AllowTTL = fc.AllowTTL.Duration
+			}
+
+			if fc.PartialTTL != nil && !fs.Changed("cache.ttl.partial") {
+				t.PartialTTL = fc.PartialTTL.Duration
+			}
+
+			if fc.DenyTTL != nil && !fs.Changed("cache.ttl.deny") {
+				t.DenyTTL = fc.DenyTTL.Duration
+			}
+		}
+
+		if fm := ft.Matcher; fm != nil {
+			m := c.tenantMatcher(ft.Name, fm, fs)
+			if err := m.validate(); err != nil {
+				return fmt.Errorf("tenant %q: %w", ft.Name, err)
+			}
+
+			matcher.tenants[ft.Name] = m
+		}
+
+		c.Tenants[ft.Name] = t
+	}
+
+	c.matcher = &matcher
+
+	return nil
+}
+
+// tenantMatcher returns the label matcher of a tenant. Settings missing from the
+// configuration file or given on the command line are taken from the flags.
+func (c *Config) tenantMatcher(name string, fm *FileMatcher, fs *flag.FlagSet) *Matcher {
+	m := c.Opa.ToMatcher()
+
+	if len(fm.Keys) > 0 && !fs.Changed("opa.matcher") {
+		m.Keys = slices.Clone(fm.Keys)
+	}
+
+	if fm.Op != "" && !fs.Changed("opa.matcher-op") {
+		m.MatcherOp = MatcherOp(fm.Op)
+	}
+
+	if len(fm.AdminGroups) > 0 && !fs.Changed("opa.admin-groups") {
+		m.adminGroups = toSet(fm.AdminGroups)
+	}
+
+	if len(fm.LabelAliases) > 0 && !fs.Changed("opa.label-aliases") {
+		m.aliases = slices.Clone(fm.LabelAliases)
+	}
+
+	if fm.ViaQToOTELMigration != nil && !fs.Changed("opa.viaq-to-otel-migration") {
+		m.viaQToOTEL = *fm.ViaQToOTELMigration
+	}
+
+	m.addViaQToOTELKeys()
+
+	if fm.Skip {
+		if m.skipTenants == nil {
+			m.skipTenants = map[string]struct{}{}
+		}
+
+		m.skipTenants[name] = struct{}{}
+	}
+
+	return &m
+}
+
+func toSet(values []string) map[string]struct{} {
+	if len(values) == 0 {
+		return nil
+	}
+
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+
+	return set
+}
@@ -2,28 +2,76 @@ package config
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"os"
+	"sync/atomic"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"k8s.io/component-base/cli/flag"
 )
 
-// NewServerConfig provides new server TLS configuration.
-func NewServerConfig(logger log.Logger, certFile, keyFile, minVersion string, cipherSuites []string) (*tls.Config, error) {
-	if certFile == "" && keyFile == "" {
-		level.Info(logger).Log("msg", "TLS disabled; key and cert must be set to enable") //nolint:errcheck
+var errInvalidCA = errors.New("no certificates found in CA file")
 
+// KeyPair serves a certificate and its key loaded from files. Reloading the files
+// replaces the served certificate, so that rotated certificates are picked up
+// without a restart.
+type KeyPair struct {
+	certFile, keyFile string
+	cert              atomic.Pointer[tls.Certificate]
+}
+
+// NewKeyPair loads the certificate and key of the given files. It returns nil
+// when neither file is given.
+func NewKeyPair(certFile, keyFile string) (*KeyPair, error) {
+	if certFile == "" && keyFile == "" {
 		return nil, nil
 	}
 
-	level.Info(logger).Log("msg", "enabling server side TLS") //nolint:errcheck
+	kp := &KeyPair{certFile: certFile, keyFile: keyFile}
+	if err := kp.Reload(); err != nil {
+		return nil, err
+	}
 
-	tlsCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	return kp, nil
+}
+
+// Reload loads the certificate and key files again, keeping the previous
+// certificate when they are invalid.
+func (kp *KeyPair) Reload() error {
+	cert, err := tls.LoadX509KeyPair(kp.certFile, kp.keyFile)
 	if err != nil {
-		return nil, fmt.Errorf("server credentials: %w", err)
+		return fmt.Errorf("server credentials: %w", err)
+	}
+
+	kp.cert.Store(&cert)
+
+	return nil
+}
+
+// Files returns the certificate and key files.
+func (kp *KeyPair) Files() []string {
+	return []string{kp.certFile, kp.keyFile}
+}
+
+// GetCertificate returns the current certificate for tls.Config.GetCertificate.
+func (kp *KeyPair) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return kp.cert.Load(), nil
+}
+
+// NewServerConfig provides new server TLS configuration serving the certificate
+// of the given KeyPair. TLS is disabled when the KeyPair is nil.
+func NewServerConfig(logger log.Logger, kp *KeyPair, minVersion string, cipherSuites []string) (*tls.Config, error) {
+	if kp == nil {
+		level.Info(logger).Log("msg", "TLS disabled; key and cert must be set to enable") //nolint:errcheck
+
+		return nil, nil
 	}
 
+	level.Info(logger).Log("msg", "enabling server side TLS") //nolint:errcheck
+
 	version, err := flag.TLSVersion(minVersion)
 	if err != nil {
 		return nil, fmt.Errorf("TLS version invalid: %w", err)
@@ -35,7 +83,7 @@ func NewServerConfig(logger log.Logger, certFile, keyFile, minVersion string, ci
 	}
 
 	tlsCfg := &tls.Config{
-		Certificates: []tls.Certificate{tlsCert},
+		GetCertificate: kp.GetCertificate,
 		// A list of supported cipher suites for TLS versions up to TLS 1.2.
 		// If CipherSuites is nil, a default list of secure cipher suites is used.
 		// Note that TLS 1.3 ciphersuites are not configurable.
@@ -46,3 +94,42 @@ func NewServerConfig(logger log.Logger, certFile, keyFile, minVersion string, ci
 
 	return tlsCfg, nil
 }
+
+// NewClientConfig provides new client TLS configuration, verifying servers against
+// the given CA, or the system certificates when caFile is empty, and presenting
+// the given client certificate when certFile and keyFile are set.
+func NewClientConfig(caFile, certFile, keyFile, serverName string, insecureSkipVerify bool, minVersion string) (*tls.Config, error) {
+	version, err := flag.TLSVersion(minVersion)
+	if err != nil {
+		return nil, fmt.Errorf("TLS version invalid: %w", err)
+	}
+
+	tlsCfg := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: insecureSkipVerify, //nolint:gosec
+		MinVersion:         version,
+	}
+
+	if caFile != "" {
+		caCert, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+
+		tlsCfg.RootCAs = x509.NewCertPool()
+		if !tlsCfg.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("%w: %s", errInvalidCA, caFile)
+		}
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client credentials: %w", err)
+		}
+
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
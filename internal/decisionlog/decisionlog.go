@@ -0,0 +1,285 @@
+// Package decisionlog ships authorization decisions in the format of the OPA
+// decision log plugin, so that existing OPA log pipelines can ingest them.
+package decisionlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/observatorium/opa-openshift/internal/audit"
+)
+
+var (
+	errBufferFull       = errors.New("decision log buffer full")
+	errUnexpectedStatus = errors.New("unexpected status code")
+)
+
+// Event is a decision log event in the shape of the OPA decision log plugin EventV1.
+type Event struct {
+	Labels      map[string]string      `json:"labels"`
+	DecisionID  string                 `json:"decision_id"`
+	Path        string                 `json:"path,omitempty"`
+	Input       *interface{}           `json:"input,omitempty"`
+	Result      *interface{}           `json:"result,omitempty"`
+	Error       *Error                 `json:"error,omitempty"`
+	RequestedBy string                 `json:"requested_by,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Metrics     map[string]interface{} `json:"metrics,omitempty"`
+}
+
+// Error describes a failed decision.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Options configures the shipping of decision log events.
+type Options struct {
+	// URL is the endpoint the gzipped batches of events are posted to.
+	URL string
+	// Labels are added to every event, e.g. the id and version of the instance.
+	Labels map[string]string
+	// BufferSize is the number of events buffered before new events are dropped.
+	BufferSize int
+	// BatchSize is the maximum number of events sent in a single request.
+	BatchSize int
+	// FlushInterval is the interval at which buffered events are sent.
+	FlushInterval time.Duration
+	// Timeout is the timeout of a single upload request.
+	Timeout time.Duration
+	// MaxRetries is the number of retries of a failed upload before the batch is spooled.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubling for every further retry.
+	RetryBackoff time.Duration
+	// SpoolDir is the directory failed batches are written to and later resent from.
+	// Failed batches are dropped when empty.
+	SpoolDir string
+	// MaxSpoolFiles is the maximum number of spooled batches, dropping the oldest first.
+	MaxSpoolFiles int
+}
+
+// Logger buffers decision log events and ships them in batches.
+type Logger struct {
+	opts   Options
+	client *http.Client
+	logger log.Logger
+	spool  *spool
+
+	events chan Event
+}
+
+// New returns a Logger shipping events with the given client once Run is called.
+func New(l log.Logger, client *http.Client, opts Options) (*Logger, error) {
+	dl := &Logger{
+		opts:   opts,
+		client: client,
+		logger: l,
+		events: make(chan Event, opts.BufferSize),
+	}
+
+	if opts.SpoolDir != "" {
+		s, err := newSpool(opts.SpoolDir, opts.MaxSpoolFiles)
+		if err != nil {
+			return nil, err
+		}
+
+		dl.spool = s
+	}
+
+	return dl, nil
+}
+
+// Log buffers the event for shipping, assigning it a decision ID, a timestamp and
+// the configured labels when missing. The given secrets and any token found in the
+// error of the event are redacted. Events are dropped when the buffer is full.
+// It is a no-op on a nil Logger.
+func (dl *Logger) Log(ev Event, secrets ...string) {
+	if dl == nil {
+		return
+	}
+
+	if ev.Error != nil {
+		ev.Error = &Error{Code: ev.Error.Code, Message: audit.Redact(ev.Error.Message, secrets...)}
+	}
+
+	if ev.DecisionID == "" {
+		ev.DecisionID = uuid.NewString()
+	}
+
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now()
+	}
+
+	if ev.Labels == nil {
+		ev.Labels = dl.opts.Labels
+	}
+
+	select {
+	case dl.events <- ev:
+	default:
+		level.Warn(dl.logger).Log("msg", "dropping decision log event", "err", errBufferFull, "decision_id", ev.DecisionID) //nolint:errcheck
+	}
+}
+
+// Run ships the buffered events until the context is done, flushing the
+// remaining events before returning.
+func (dl *Logger) Run(ctx context.Context) error {
+	ticker := time.NewTicker(dl.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, dl.opts.BatchSize)
+
+	for {
+		select {
+		case ev := <-dl.events:
+			batch = append(batch, ev)
+			if len(batch) < dl.opts.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			dl.resendSpooled(ctx)
+		case <-ctx.Done():
+			dl.drain(batch)
+			return nil
+		}
+
+		dl.send(ctx, batch, dl.opts.MaxRetries)
+		batch = batch[:0]
+	}
+}
+
+// drain sends the events left in the buffer without retries, spooling them on failure.
+func (dl *Logger) drain(batch []Event) {
+	for {
+		select {
+		case ev := <-dl.events:
+			batch = append(batch, ev)
+		default:
+			for len(batch) > 0 {
+				n := min(len(batch), dl.opts.BatchSize)
+				dl.send(context.Background(), batch[:n], 0)
+				batch = batch[n:]
+			}
+
+			return
+		}
+	}
+}
+
+// send uploads the batch, retrying up to the given number of times before spooling it.
+func (dl *Logger) send(ctx context.Context, batch []Event, retries int) {
+	if len(batch) == 0 {
+		return
+	}
+
+	payload, err := encode(batch)
+	if err != nil {
+		level.Warn(dl.logger).Log("msg", "dropping decision log batch", "err", err) //nolint:errcheck
+		return
+	}
+
+	if err := dl.uploadWithRetry(ctx, payload, retries); err != nil {
+		dl.spoolPayload(payload, len(batch), err)
+		return
+	}
+
+	level.Debug(dl.logger).Log("msg", "shipped decision log batch", "events", len(batch)) //nolint:errcheck
+}
+
+func (dl *Logger) spoolPayload(payload []byte, events int, cause error) {
+	if dl.spool == nil {
+		level.Warn(dl.logger).Log("msg", "dropping decision log batch", "events", events, "err", cause) //nolint:errcheck
+		return
+	}
+
+	if err := dl.spool.write(payload); err != nil {
+		level.Warn(dl.logger).Log("msg", "dropping decision log batch", "events", events, "err", err) //nolint:errcheck
+		return
+	}
+
+	level.Warn(dl.logger).Log("msg", "spooled decision log batch", "events", events, "err", cause) //nolint:errcheck
+}
+
+// resendSpooled uploads the spooled batches oldest first, stopping at the first failure.
+func (dl *Logger) resendSpooled(ctx context.Context) {
+	if dl.spool == nil {
+		return
+	}
+
+	err := dl.spool.each(func(payload []byte) error {
+		return dl.upload(ctx, payload)
+	})
+	if err != nil {
+		level.Debug(dl.logger).Log("msg", "failed to resend spooled decision logs", "err", err) //nolint:errcheck
+	}
+}
+
+func (dl *Logger) uploadWithRetry(ctx context.Context, payload []byte, retries int) error {
+	backoff := dl.opts.RetryBackoff
+
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		if err = dl.upload(ctx, payload); err == nil || attempt >= retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+func (dl *Logger) upload(ctx context.Context, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, dl.opts.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.opts.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create decision log request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+
+	res, err := dl.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload decision logs: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode/100 != 2 { //nolint:gomnd
+		return fmt.Errorf("%w: %d", errUnexpectedStatus, res.StatusCode)
+	}
+
+	return nil
+}
+
+// encode returns the batch as a gzipped JSON array, as sent by the OPA decision log plugin.
+func encode(batch []Event) ([]byte, error) {
+	var buf bytes.Buffer
+
+	gw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gw).Encode(batch); err != nil {
+		return nil, fmt.Errorf("failed to encode decision logs: %w", err)
+	}
+
+	if err := gw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress decision logs: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
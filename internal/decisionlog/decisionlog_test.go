@@ -0,0 +1,190 @@
+package decisionlog
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+type receiver struct {
+	mu      sync.Mutex
+	batches [][]Event
+	fail    atomic.Bool
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if rc.fail.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Header.Get("Content-Encoding") != "gzip" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	gr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var batch []Event
+	if err := json.NewDecoder(gr).Decode(&batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rc.mu.Lock()
+	rc.batches = append(rc.batches, batch)
+	rc.mu.Unlock()
+}
+
+func (rc *receiver) events() []Event {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	events := []Event{}
+	for _, b := range rc.batches {
+		events = append(events, b...)
+	}
+
+	return events
+}
+
+func newTestLogger(t *testing.T, url, spoolDir string) *Logger {
+	t.Helper()
+
+	dl, err := New(log.NewNopLogger(), http.DefaultClient, Options{
+		URL:           url,
+		Labels:        map[string]string{"id": "test"},
+		BufferSize:    100,
+		BatchSize:     2,
+		FlushInterval: 10 * time.Millisecond,
+		Timeout:       time.Second,
+		MaxRetries:    1,
+		RetryBackoff:  time.Millisecond,
+		SpoolDir:      spoolDir,
+	})
+	require.NoError(t, err)
+
+	return dl
+}
+
+func TestLogger_ShipsBatches(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	dl := newTestLogger(t, srv.URL, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- dl.Run(ctx) }()
+
+	var result interface{} = true
+	for range 3 {
+		dl.Log(Event{Path: "observatorium/allow", Result: &result})
+	}
+
+	require.Eventually(t, func() bool { return len(rc.events()) == 3 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	rc.mu.Lock()
+	require.Len(t, rc.batches[0], 2)
+	rc.mu.Unlock()
+
+	ev := rc.events()[0]
+	require.NotEmpty(t, ev.DecisionID)
+	require.False(t, ev.Timestamp.IsZero())
+	require.Equal(t, map[string]string{"id": "test"}, ev.Labels)
+	require.Equal(t, "observatorium/allow", ev.Path)
+	require.Equal(t, true, *ev.Result)
+}
+
+func TestLogger_RedactsErrors(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	dl := newTestLogger(t, srv.URL, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- dl.Run(ctx) }()
+
+	ev := Event{Path: "observatorium/allow", Error: &Error{Code: 500, Message: "token my-secret rejected: Bearer sha256~abc"}}
+	dl.Log(ev, "my-secret")
+	dl.Log(ev, "my-secret")
+
+	require.Eventually(t, func() bool { return len(rc.events()) == 2 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	require.Equal(t, &Error{Code: 500, Message: "token [REDACTED] rejected: Bearer [REDACTED]"}, rc.events()[0].Error)
+	require.Equal(t, "token my-secret rejected: Bearer sha256~abc", ev.Error.Message)
+}
+
+func TestLogger_SpoolsFailedBatches(t *testing.T) {
+	rc := &receiver{}
+	rc.fail.Store(true)
+
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	dl := newTestLogger(t, srv.URL, dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- dl.Run(ctx) }()
+
+	dl.Log(Event{Path: "observatorium/allow"})
+	dl.Log(Event{Path: "observatorium/allow"})
+
+	require.Eventually(t, func() bool {
+		entries, err := os.ReadDir(dir)
+		return err == nil && len(entries) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	rc.fail.Store(false)
+
+	require.Eventually(t, func() bool { return len(rc.events()) == 2 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestLogger_FlushesOnShutdown(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	dl := newTestLogger(t, srv.URL, "")
+	dl.opts.FlushInterval = time.Hour
+
+	dl.Log(Event{Path: "observatorium/allow"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, dl.Run(ctx))
+
+	require.Len(t, rc.events(), 1)
+}
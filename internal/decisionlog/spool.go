@@ -0,0 +1,106 @@
+package decisionlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	spoolSuffix   = ".json.gz"
+	spoolDirPerm  = 0o700
+	spoolFilePerm = 0o600
+)
+
+// spool keeps batches that could not be uploaded on disk until they can be resent.
+type spool struct {
+	mu       sync.Mutex
+	dir      string
+	maxFiles int
+}
+
+func newSpool(dir string, maxFiles int) (*spool, error) {
+	if err := os.MkdirAll(dir, spoolDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create decision log spool directory: %w", err)
+	}
+
+	return &spool{dir: dir, maxFiles: maxFiles}, nil
+}
+
+// write stores the payload, dropping the oldest spooled batches beyond the maximum.
+func (s *spool) write(payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := filepath.Join(s.dir, fmt.Sprintf("%020d%s", time.Now().UnixNano(), spoolSuffix))
+	if err := os.WriteFile(name, payload, spoolFilePerm); err != nil {
+		return fmt.Errorf("failed to spool decision logs: %w", err)
+	}
+
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	for s.maxFiles > 0 && len(files) > s.maxFiles {
+		if err := os.Remove(files[0]); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to drop spooled decision logs: %w", err)
+		}
+
+		files = files[1:]
+	}
+
+	return nil
+}
+
+// each calls fn with every spooled batch, oldest first, removing the batches fn
+// succeeded for. It stops at the first error.
+func (s *spool) each(fn func([]byte) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		payload, err := os.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("failed to read spooled decision logs: %w", err)
+		}
+
+		if err := fn(payload); err != nil {
+			return err
+		}
+
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove spooled decision logs: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *spool) files() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spooled decision logs: %w", err)
+	}
+
+	files := []string{}
+
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), spoolSuffix) {
+			files = append(files, filepath.Join(s.dir, e.Name()))
+		}
+	}
+
+	slices.Sort(files)
+
+	return files, nil
+}
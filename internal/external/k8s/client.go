@@ -50,3 +50,12 @@ type SelfSubjectAccessReviewInterface interface {
 	//nolint:lll
 	Create(ctx context.Context, selfSubjectAccessReview *authorizationapiv1.SelfSubjectAccessReview, opts metav1.CreateOptions) (*authorizationapiv1.SelfSubjectAccessReview, error)
 }
+
+// Client is a kubernetes clientset interface used internally. It copies functions from
+// k8s.io/client-go/kubernetes
+//
+//counterfeiter:generate . SelfSubjectRulesReviewInterface
+type SelfSubjectRulesReviewInterface interface {
+	//nolint:lll
+	Create(ctx context.Context, selfSubjectRulesReview *authorizationapiv1.SelfSubjectRulesReview, opts metav1.CreateOptions) (*authorizationapiv1.SelfSubjectRulesReview, error)
+}
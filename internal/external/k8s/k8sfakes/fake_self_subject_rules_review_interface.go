@@ -0,0 +1,121 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package k8sfakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/observatorium/opa-openshift/internal/external/k8s"
+	v1 "k8s.io/api/authorization/v1"
+	v1a "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type FakeSelfSubjectRulesReviewInterface struct {
+	CreateStub        func(context.Context, *v1.SelfSubjectRulesReview, v1a.CreateOptions) (*v1.SelfSubjectRulesReview, error)
+	createMutex       sync.RWMutex
+	createArgsForCall []struct {
+		arg1 context.Context
+		arg2 *v1.SelfSubjectRulesReview
+		arg3 v1a.CreateOptions
+	}
+	createReturns struct {
+		result1 *v1.SelfSubjectRulesReview
+		result2 error
+	}
+	createReturnsOnCall map[int]struct {
+		result1 *v1.SelfSubjectRulesReview
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeSelfSubjectRulesReviewInterface) Create(arg1 context.Context, arg2 *v1.SelfSubjectRulesReview, arg3 v1a.CreateOptions) (*v1.SelfSubjectRulesReview, error) {
+	fake.createMutex.Lock()
+	ret, specificReturn := fake.createReturnsOnCall[len(fake.createArgsForCall)]
+	fake.createArgsForCall = append(fake.createArgsForCall, struct {
+		arg1 context.Context
+		arg2 *v1.SelfSubjectRulesReview
+		arg3 v1a.CreateOptions
+	}{arg1, arg2, arg3})
+	stub := fake.CreateStub
+	fakeReturns := fake.createReturns
+	fake.recordInvocation("Create", []interface{}{arg1, arg2, arg3})
+	fake.createMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSelfSubjectRulesReviewInterface) CreateCallCount() int {
+	fake.createMutex.RLock()
+	defer fake.createMutex.RUnlock()
+	return len(fake.createArgsForCall)
+}
+
+func (fake *FakeSelfSubjectRulesReviewInterface) CreateCalls(stub func(context.Context, *v1.SelfSubjectRulesReview, v1a.CreateOptions) (*v1.SelfSubjectRulesReview, error)) {
+	fake.createMutex.Lock()
+	defer fake.createMutex.Unlock()
+	fake.CreateStub = stub
+}
+
+func (fake *FakeSelfSubjectRulesReviewInterface) CreateArgsForCall(i int) (context.Context, *v1.SelfSubjectRulesReview, v1a.CreateOptions) {
+	fake.createMutex.RLock()
+	defer fake.createMutex.RUnlock()
+	argsForCall := fake.createArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeSelfSubjectRulesReviewInterface) CreateReturns(result1 *v1.SelfSubjectRulesReview, result2 error) {
+	fake.createMutex.Lock()
+	defer fake.createMutex.Unlock()
+	fake.CreateStub = nil
+	fake.createReturns = struct {
+		result1 *v1.SelfSubjectRulesReview
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSelfSubjectRulesReviewInterface) CreateReturnsOnCall(i int, result1 *v1.SelfSubjectRulesReview, result2 error) {
+	fake.createMutex.Lock()
+	defer fake.createMutex.Unlock()
+	fake.CreateStub = nil
+	if fake.createReturnsOnCall == nil {
+		fake.createReturnsOnCall = make(map[int]struct {
+			result1 *v1.SelfSubjectRulesReview
+			result2 error
+		})
+	}
+	fake.createReturnsOnCall[i] = struct {
+		result1 *v1.SelfSubjectRulesReview
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSelfSubjectRulesReviewInterface) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeSelfSubjectRulesReviewInterface) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ k8s.SelfSubjectRulesReviewInterface = new(FakeSelfSubjectRulesReviewInterface)
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/observatorium/opa-openshift/internal/authorizer"
+	"github.com/observatorium/opa-openshift/internal/cache"
+)
+
+// AdminCachePrefix is the path prefix of the endpoints served by NewAdmin.
+const AdminCachePrefix = "/admin/cache/"
+
+type cacheEntry struct {
+	Key    string       `json:"key"`
+	Result *interface{} `json:"result,omitempty"`
+}
+
+type purgeResponse struct {
+	Purged int `json:"purged"`
+}
+
+// NewAdmin returns a handler inspecting and purging the decisions of the given cache:
+//
+//	GET    /admin/cache/stats                            counts the indexed keys, subjects and tenants
+//	GET    /admin/cache/entries?subject=|tenant=|prefix= lists the matching entries
+//	DELETE /admin/cache/entries?subject=|tenant=|all=true purges the matching entries
+//
+// Lookups only cover the keys indexed by the replica serving the request, i.e. the
+// entries it stored or served. Purges bump the epochs of the subject, the tenant or of
+// all entries in the shared cache, so that the matching decisions of every replica
+// become unreachable, and report the number of entries deleted from the index.
+// Subjects are looked up by their digest of the given SubjectHasher, which must be
+// the one of the authorizers. Every request must carry the given token as a bearer token.
+func NewAdmin(l log.Logger, c *cache.Indexed, subjects *authorizer.SubjectHasher, token string) http.HandlerFunc {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET "+AdminCachePrefix+"stats", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, c.Stats())
+	})
+
+	mux.HandleFunc("GET "+AdminCachePrefix+"entries", func(w http.ResponseWriter, r *http.Request) {
+		var keys []string
+
+		q := r.URL.Query()
+
+		switch {
+		case q.Has("subject"):
+			keys = c.Keys(subjects.Tag(q.Get("subject")))
+		case q.Has("tenant"):
+			keys = c.Keys(authorizer.TenantTag(q.Get("tenant")))
+		case q.Has("prefix"):
+			keys = c.KeysWithPrefix(q.Get("prefix"))
+		default:
+			writeError(w, "one of subject, tenant or prefix is required", http.StatusBadRequest)
+			return //nolint:nlreturn
+		}
+
+		entries := make([]cacheEntry, 0, len(keys))
+
+		for _, k := range keys {
+			res, ok, err := c.Get(k)
+			if err != nil {
+				level.Warn(l).Log("msg", "failed to fetch cache entry", "err", err) //nolint:errcheck
+				continue
+			}
+
+			if ok {
+				entries = append(entries, cacheEntry{Key: k, Result: res.Result})
+			}
+		}
+
+		writeJSON(w, entries)
+	})
+
+	mux.HandleFunc("DELETE "+AdminCachePrefix+"entries", func(w http.ResponseWriter, r *http.Request) {
+		var (
+			purged int
+			err    error
+		)
+
+		q := r.URL.Query()
+
+		switch {
+		case q.Has("subject"):
+			purged, err = c.Purge(subjects.Tag(q.Get("subject")))
+		case q.Has("tenant"):
+			purged, err = c.Purge(authorizer.TenantTag(q.Get("tenant")))
+		case q.Get("all") == "true":
+			purged, err = c.PurgeAll()
+		default:
+			writeError(w, "one of subject, tenant or all=true is required", http.StatusBadRequest)
+			return //nolint:nlreturn
+		}
+
+		if err != nil {
+			level.Error(l).Log("msg", "failed to purge cache", "query", r.URL.RawQuery, "err", err) //nolint:errcheck
+			writeError(w, "failed to purge cache", http.StatusInternalServerError)
+
+			return
+		}
+
+		level.Info(l).Log("msg", "purged cached decisions", "query", r.URL.RawQuery, "purged", purged) //nolint:errcheck
+		writeJSON(w, purgeResponse{Purged: purged})
+	})
+
+	want := []byte("Bearer " + token)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeError(w, "a valid bearer token is required", http.StatusUnauthorized)
+			return //nolint:nlreturn
+		}
+
+		mux.ServeHTTP(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/observatorium/opa-openshift/internal/authorizer"
+	"github.com/observatorium/opa-openshift/internal/cache"
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAdmin(t *testing.T) {
+	backend := cache.NewInMemoryCache(60, 0, 0)
+	c := cache.NewIndexed(backend, cache.NewEpochs(backend), time.Minute, 0)
+
+	var allowed interface{} = true
+	res := types.DataResponseV1{Result: &allowed}
+
+	subjects := authorizer.NewSubjectHasher([]byte("secret"))
+
+	// key returns a decision cache key of the given user.
+	key := func(apiGroup, tenant, user string) string {
+		return strings.Join([]string{"get", "false", apiGroup, "logs", tenant, "ns-a", user}, ",")
+	}
+
+	stored := []struct {
+		name, apiGroup, tenant, user string
+	}{
+		{name: "alice-logs", apiGroup: "loki.grafana.com", tenant: "application", user: "alice"},
+		{name: "alice-metrics", apiGroup: "monitoring", tenant: "tenant-b", user: "alice"},
+		{name: "bob-logs", apiGroup: "loki.grafana.com", tenant: "application", user: "bob"},
+	}
+
+	keys := map[string]string{}
+	for _, e := range stored {
+		keys[e.name] = key(e.apiGroup, e.tenant, e.user)
+		require.NoError(t, c.SetTagged(keys[e.name], res, 0, []string{subjects.Tag(e.user), authorizer.TenantTag(e.tenant)}))
+	}
+
+	h := NewAdmin(log.NewNopLogger(), c, subjects, "secret")
+
+	do := func(method, target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	for _, tc := range []struct {
+		desc     string
+		method   string
+		target   string
+		token    string
+		wantCode int
+	}{
+		{desc: "missing token", method: http.MethodGet, target: "/admin/cache/stats", wantCode: http.StatusUnauthorized},
+		{desc: "invalid token", method: http.MethodGet, target: "/admin/cache/stats", token: "wrong", wantCode: http.StatusUnauthorized},
+		{desc: "stats", method: http.MethodGet, target: "/admin/cache/stats", token: "secret", wantCode: http.StatusOK},
+		{desc: "lookup without selector", method: http.MethodGet, target: "/admin/cache/entries", token: "secret", wantCode: http.StatusBadRequest},
+		{desc: "purge without selector", method: http.MethodDelete, target: "/admin/cache/entries", token: "secret", wantCode: http.StatusBadRequest},
+		{desc: "unsupported method", method: http.MethodPost, target: "/admin/cache/entries", token: "secret", wantCode: http.StatusMethodNotAllowed},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.Equal(t, tc.wantCode, do(tc.method, tc.target, tc.token).Code)
+		})
+	}
+
+	var stats cache.IndexStats
+
+	rec := do(http.MethodGet, "/admin/cache/stats", "secret")
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+	require.Equal(t, cache.IndexStats{Keys: 3, Tags: map[string]int{"subject": 2, "tenant": 2}}, stats)
+
+	var entries []cacheEntry
+
+	rec = do(http.MethodGet, "/admin/cache/entries?subject=alice", "secret")
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&entries))
+	require.Len(t, entries, 2)
+	require.Equal(t, true, *entries[0].Result)
+
+	rec = do(http.MethodGet, "/admin/cache/entries?prefix=get,false,monitoring", "secret")
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&entries))
+	require.Len(t, entries, 1)
+	require.Equal(t, keys["alice-metrics"], entries[0].Key)
+
+	var purged purgeResponse
+
+	rec = do(http.MethodDelete, "/admin/cache/entries?tenant=application", "secret")
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&purged))
+	require.Equal(t, 2, purged.Purged)
+
+	_, ok, err := c.Get(keys["bob-logs"])
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	rec = do(http.MethodDelete, "/admin/cache/entries?all=true", "secret")
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&purged))
+	require.Equal(t, 1, purged.Purged)
+
+	_, ok, err = c.Get(keys["alice-metrics"])
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -1,22 +1,33 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/observatorium/opa-openshift/internal/audit"
 	"github.com/observatorium/opa-openshift/internal/authorizer"
 	"github.com/observatorium/opa-openshift/internal/cache"
 	"github.com/observatorium/opa-openshift/internal/config"
+	"github.com/observatorium/opa-openshift/internal/decisionlog"
 	"github.com/observatorium/opa-openshift/internal/openshift"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"k8s.io/apimachinery/pkg/util/sets"
-	"k8s.io/client-go/transport"
 )
 
 const (
 	xForwardedAccessTokenHeader = "X-Forwarded-Access-Token" //nolint:gosec
+
+	dataAPIPrefix = "/v1/data/"
+
+	tracerName = "github.com/observatorium/opa-openshift/internal/handler"
 )
 
 // Permission is an Observatorium RBAC permission.
@@ -48,22 +59,98 @@ type dataRequestV1 struct {
 	Input Input `json:"input"`
 }
 
-//nolint:cyclop,gocognit
-func New(l log.Logger, c cache.Cacher, wt transport.WrapperFunc, cfg *config.Config) http.HandlerFunc {
-	kubeconfigPath := cfg.KubeconfigPath
-	tenantAPIGroups := cfg.Mappings
+// errorResponse is the body written for requests that could not be authorized.
+type errorResponse struct {
+	Code    int    `json:"code"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// Option configures optional behavior of the handler.
+type Option func(*options)
+
+type options struct {
+	authorizerOpts []authorizer.Option
+	auditLogger    *audit.Logger
+	decisionLogger *decisionlog.Logger
+	tracerProvider trace.TracerProvider
+	metrics        *DecisionMetrics
+	config         func() *config.Config
+}
+
+// WithAuthorizerOptions applies the given options to the authorizer of every request.
+func WithAuthorizerOptions(opts ...authorizer.Option) Option {
+	return func(o *options) {
+		o.authorizerOpts = append(o.authorizerOpts, opts...)
+	}
+}
+
+// WithAuditLogger records an audit event for every decision.
+func WithAuditLogger(al *audit.Logger) Option {
+	return func(o *options) {
+		o.auditLogger = al
+	}
+}
+
+// WithDecisionLogger ships every decision as an OPA decision log event.
+func WithDecisionLogger(dl *decisionlog.Logger) Option {
+	return func(o *options) {
+		o.decisionLogger = dl
+	}
+}
+
+// WithTracerProvider records spans for decoding requests and for the authorizer.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+		o.authorizerOpts = append(o.authorizerOpts, authorizer.WithTracerProvider(tp))
+	}
+}
+
+// WithConfig resolves the tenants of every request with the configuration returned
+// by the given function, e.g. to pick up tenants of a reloaded configuration file.
+func WithConfig(f func() *config.Config) Option {
+	return func(o *options) {
+		o.config = f
+	}
+}
+
+// WithDecisionMetrics records the outcome and cost of every decision.
+func WithDecisionMetrics(m *DecisionMetrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+//nolint:cyclop,gocognit,funlen
+func New(l log.Logger, c cache.Cacher, cp openshift.ClientProvider, cfg *config.Config, opts ...Option) http.HandlerFunc {
 	debugToken := cfg.DebugToken
-	matcher := cfg.Opa.ToMatcher()
+
+	o := &options{
+		tracerProvider: noop.NewTracerProvider(),
+		config:         func() *config.Config { return cfg },
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	tracer := o.tracerProvider.Tracer(tracerName)
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		current := o.config()
+
 		if r.Method != http.MethodPost {
-			http.Error(w, "request must be a POST", http.StatusBadRequest)
+			writeError(w, "request must be a POST", http.StatusBadRequest)
 			return //nolint:nlreturn
 		}
 
+		_, span := tracer.Start(r.Context(), "decode")
+
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
-			http.Error(w, "failed to read body", http.StatusInternalServerError)
+			span.End()
+			writeError(w, "failed to read body", http.StatusInternalServerError)
 			return //nolint:nlreturn
 		}
 		defer func() { _ = r.Body.Close() }()
@@ -71,19 +158,54 @@ func New(l log.Logger, c cache.Cacher, wt transport.WrapperFunc, cfg *config.Con
 		var req dataRequestV1
 
 		err = json.Unmarshal(body, &req)
+		span.End()
+
 		if err != nil {
-			http.Error(w, "failed to unmarshal JSON", http.StatusInternalServerError)
+			writeError(w, "failed to unmarshal JSON", http.StatusInternalServerError)
 			return //nolint:nlreturn
 		}
 
-		apiGroup, ok := tenantAPIGroups[req.Input.Tenant]
+		var (
+			token   string
+			result  *interface{}
+			errCode int
+			reviews int
+		)
+
+		ev := audit.Event{
+			Time:       start,
+			Subject:    req.Input.Subject,
+			Groups:     req.Input.Groups,
+			Tenant:     req.Input.Tenant,
+			Permission: string(req.Input.Permission),
+			Resource:   req.Input.Resource,
+		}
+		defer func() {
+			latency := time.Since(start)
+
+			ev.LatencySeconds = latency.Seconds()
+			o.auditLogger.Log(ev, token)
+
+			o.decisionLogger.Log(newDecisionLogEvent(r, req.Input, result, errCode, ev.Error, start, latency), token)
+
+			tenant, permission := metricLabels(current.Mappings, req.Input)
+			o.metrics.observe(tenant, permission, ev.Decision, latency, len(ev.Namespaces), reviews)
+		}()
+
+		fail := func(msg string, code int) {
+			errCode = code
+			ev.Decision, ev.ErrorClass, ev.Error = audit.DecisionError, errorClass(code), msg
+			writeError(w, msg, code)
+		}
+
+		tenant, ok := current.Tenant(req.Input.Tenant)
 		if !ok {
-			http.Error(w, "unknown tenant", http.StatusInternalServerError)
+			fail("unknown tenant", http.StatusInternalServerError)
 			return //nolint:nlreturn
 		}
 
 		if req.Input.Resource == "" {
-			http.Error(w, "unknown resource", http.StatusBadRequest)
+			fail("unknown resource", http.StatusBadRequest)
 			return //nolint:nlreturn
 		}
 
@@ -95,14 +217,14 @@ func New(l log.Logger, c cache.Cacher, wt transport.WrapperFunc, cfg *config.Con
 		case Write:
 			verb = authorizer.CreateVerb
 		default:
-			http.Error(w, "unknown permission", http.StatusBadRequest)
+			fail("unknown permission", http.StatusBadRequest)
 			return //nolint:nlreturn
 		}
 
-		token := r.Header.Get(xForwardedAccessTokenHeader)
+		token = r.Header.Get(xForwardedAccessTokenHeader)
 		if token == "" {
 			if debugToken == "" {
-				http.Error(w, "missing forwarded access token", http.StatusBadRequest)
+				fail("missing forwarded access token", http.StatusBadRequest)
 
 				return
 			}
@@ -112,34 +234,30 @@ func New(l log.Logger, c cache.Cacher, wt transport.WrapperFunc, cfg *config.Con
 			level.Warn(l).Log("msg", "using debug.token in production environments is not recommended.") //nolint:errcheck
 		}
 
-		oc, err := openshift.NewClient(wt, kubeconfigPath, token, cfg.Opa.SSAR)
+		oc, err := cp.ForToken(token, tenant.SSAR)
 		if err != nil {
-			http.Error(w, "failed to create openshift client", http.StatusInternalServerError)
+			fail("failed to create openshift client", http.StatusInternalServerError)
 
 			return
 		}
 
-		matcherForRequest := matcher.ForRequest(req.Input.Tenant, req.Input.Groups)
+		tenantMatcher := current.Matcher().ForTenant(req.Input.Tenant)
+		matcherForRequest := tenantMatcher.ForRequest(req.Input.Tenant, req.Input.Groups)
 		extras := req.Input.Extras
 		if extras.WildcardSelectors && !matcherForRequest.IsEmpty() {
 			// do not allow wildcards in namespaces for everyone that needs an explicit namespace match
-			http.Error(w, "wildcard in query namespaces not allowed", http.StatusBadRequest)
+			fail("wildcard in query namespaces not allowed", http.StatusBadRequest)
 			return
 		}
 
-		// If ViaQ to OTEL migration then if extras has both
-		// kubernetes_namespace_name & k8s_namespace_name set then fail
-		if cfg.Opa.ViaQToOTELMigration {
-			if vals, ok := extras.Selectors["kubernetes_namespace_name"]; ok && len(vals) > 0 {
-				if vals, ok := extras.Selectors["k8s_namespace_name"]; ok && len(vals) > 0 {
-					http.Error(w, "queries with both 'kubernetes_namespace_name' and 'k8s_namespace_name' selectors are not allowed", http.StatusBadRequest)
-					return
-				}
-			}
-
-			matcherForRequest.ViaQToOTELMigration(extras.Selectors)
+		// Label aliases select the key of the matcher, so queries must not select several of them.
+		if a, b, mixed := tenantMatcher.MixedAliases(extras.Selectors); mixed {
+			fail(fmt.Sprintf("queries with both '%s' and '%s' selectors are not allowed", a, b), http.StatusBadRequest)
+			return //nolint:nlreturn
 		}
 
+		matcherForRequest.SelectAliases(extras.Selectors)
+
 		// Collect all "namespaces" mentioned in the selectors.
 		// We currently do not care which label the namespace value came from.
 		namespaces := sets.New[string]()
@@ -149,9 +267,38 @@ func New(l log.Logger, c cache.Cacher, wt transport.WrapperFunc, cfg *config.Con
 			}
 		}
 
-		a := authorizer.New(oc, l, c, matcherForRequest)
+		ev.Namespaces = namespaces.UnsortedList()
+
+		a := authorizer.New(oc, l, c, matcherForRequest, append([]authorizer.Option{
+			authorizer.WithSARConcurrency(cfg.OpenShift.SARConcurrency),
+			// Rules reviews evaluate the holder of the token, like self subject access reviews only.
+			authorizer.WithRulesReview(tenant.SSAR && tenant.SSRR),
+			authorizer.WithCacheTTLs(cache.TTLs{
+				Allowed: tenant.AllowTTL,
+				Partial: tenant.PartialTTL,
+				Denied:  tenant.DenyTTL,
+			}),
+			authorizer.WithNamespaceCache(cfg.Cache.NamespaceReviews),
+			authorizer.WithMatcherOptions(authorizer.MatcherOptions{
+				SingleEqual:    cfg.Opa.MatcherSingleEqual,
+				FactorPrefixes: cfg.Opa.MatcherFactorPrefixes,
+				MaxSize:        cfg.Opa.MatcherMaxSize,
+			}),
+		}, o.authorizerOpts...)...)
+
+		ctx := r.Context()
+		if cfg.OpenShift.DecisionTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.OpenShift.DecisionTimeout)
+			defer cancel()
+		}
+
+		res, err := a.Authorize(ctx, token, req.Input.Subject, req.Input.Groups, verb, req.Input.Tenant, tenant.Resource(req.Input.Resource), tenant.APIGroup, ev.Namespaces, extras.MetadataOnly)
+
+		stats := a.Stats()
+		ev.CacheHit, ev.AllowedNamespaces = stats.CacheHit, stats.AllowedNamespaces
+		reviews = stats.Reviews
 
-		res, err := a.Authorize(token, req.Input.Subject, req.Input.Groups, verb, req.Input.Tenant, req.Input.Resource, apiGroup, namespaces.UnsortedList(), extras.MetadataOnly)
 		if err != nil {
 			statusCode := http.StatusInternalServerError
 			//nolint:errorlint
@@ -159,14 +306,17 @@ func New(l log.Logger, c cache.Cacher, wt transport.WrapperFunc, cfg *config.Con
 				statusCode = sce.StatusCode()
 			}
 
-			http.Error(w, err.Error(), statusCode)
+			fail(err.Error(), statusCode)
 
 			return
 		}
 
+		ev.Decision = string(cache.OutcomeOf(res))
+		result = res.Result
+
 		out, err := json.Marshal(res)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			fail(err.Error(), http.StatusInternalServerError)
 			return //nolint:nlreturn
 		}
 
@@ -178,9 +328,83 @@ func New(l log.Logger, c cache.Cacher, wt transport.WrapperFunc, cfg *config.Con
 				statusCode = sce.StatusCode()
 			}
 
-			http.Error(w, err.Error(), statusCode)
+			fail(err.Error(), statusCode)
 
 			return
 		}
 	}
 }
+
+// metricLabels returns the tenant and permission of the request, bounding the
+// label values to the configured tenants and the known permissions.
+func metricLabels(tenantAPIGroups map[string]string, in Input) (string, string) {
+	tenant, permission := in.Tenant, string(in.Permission)
+
+	if _, ok := tenantAPIGroups[tenant]; !ok {
+		tenant = unknownLabelValue
+	}
+
+	if in.Permission != Read && in.Permission != Write {
+		permission = unknownLabelValue
+	}
+
+	return tenant, permission
+}
+
+// newDecisionLogEvent returns the OPA decision log event of the request.
+func newDecisionLogEvent(
+	r *http.Request, in Input, result *interface{}, errCode int, errMsg string,
+	start time.Time, latency time.Duration,
+) decisionlog.Event {
+	var input interface{} = in
+
+	dle := decisionlog.Event{
+		Path:        strings.TrimPrefix(r.URL.Path, dataAPIPrefix),
+		Input:       &input,
+		Result:      result,
+		RequestedBy: r.RemoteAddr,
+		Timestamp:   start,
+		Metrics: map[string]interface{}{
+			"timer_server_handler_ns": latency.Nanoseconds(),
+		},
+	}
+
+	if errCode != 0 {
+		dle.Error = &decisionlog.Error{Code: errCode, Message: errMsg}
+	}
+
+	return dle
+}
+
+// errorClass returns a short classification of an error status code for audit events.
+func errorClass(code int) string {
+	switch {
+	case code == http.StatusUnauthorized:
+		return "unauthorized"
+	case code == http.StatusForbidden:
+		return "forbidden"
+	case code == http.StatusTooManyRequests:
+		return "throttled"
+	case code == http.StatusServiceUnavailable:
+		return "unavailable"
+	case code == http.StatusGatewayTimeout:
+		return "timeout"
+	case code < http.StatusInternalServerError:
+		return "bad_request"
+	default:
+		return "internal"
+	}
+}
+
+// writeError replies to the request with the given message and status code as a JSON errorResponse.
+func writeError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(errorResponse{
+		Code:    code,
+		Reason:  strings.ReplaceAll(http.StatusText(code), " ", ""),
+		Message: msg,
+	})
+}
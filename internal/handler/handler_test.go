@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/observatorium/opa-openshift/internal/cache"
+	"github.com/observatorium/opa-openshift/internal/config"
+	"github.com/observatorium/opa-openshift/internal/openshift"
+	"github.com/observatorium/opa-openshift/internal/openshift/openshiftfakes"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClientProvider struct {
+	client openshift.Client
+	ssar   bool
+}
+
+func (f *fakeClientProvider) ForToken(_ string, ssar bool) (openshift.Client, error) {
+	f.ssar = ssar
+	return f.client, nil
+}
+
+func TestNew_DecisionMetrics(t *testing.T) {
+	c := &openshiftfakes.FakeClient{}
+	c.AccessReviewCalls(func(_ context.Context, _ string, _ []string, _, _, _, _, namespace string) (bool, error) {
+		return namespace == "ns-a", nil
+	})
+
+	cfg := &config.Config{
+		Mappings: map[string]string{"application": "loki.grafana.com"},
+		Opa:      config.OPAConfig{Matcher: "kubernetes_namespace_name"},
+	}
+
+	reg := prometheus.NewRegistry()
+	h := New(log.NewNopLogger(), cache.NewInMemoryCache(60, 0, 0), &fakeClientProvider{client: c}, cfg,
+		WithDecisionMetrics(NewDecisionMetrics(reg)),
+	)
+
+	for _, body := range []string{
+		`{"input":{"subject":"user","permission":"read","resource":"logs","tenant":"application","extras":{"selectors":{"kubernetes_namespace_name":["ns-a","ns-b"]}}}}`,
+		`{"input":{"subject":"user","permission":"read","resource":"logs","tenant":"application","extras":{"selectors":{"kubernetes_namespace_name":["ns-b"]}}}}`,
+		`{"input":{"subject":"user","permission":"read","resource":"logs","tenant":"other"}}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/data/observatorium/allow", strings.NewReader(body))
+		req.Header.Set(xForwardedAccessTokenHeader, "test-token")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	require.NoError(t, testutil.CollectAndCompare(reg, strings.NewReader(`
+# HELP opa_openshift_decisions_total Counts the number of authorization decisions by tenant, permission and result.
+# TYPE opa_openshift_decisions_total counter
+opa_openshift_decisions_total{permission="read",result="denied",tenant="application"} 1
+opa_openshift_decisions_total{permission="read",result="error",tenant="unknown"} 1
+opa_openshift_decisions_total{permission="read",result="partial",tenant="application"} 1
+# HELP opa_openshift_decision_reviews_total Counts the number of access and rules reviews issued to the API server for authorization decisions.
+# TYPE opa_openshift_decision_reviews_total counter
+opa_openshift_decision_reviews_total{permission="read",tenant="application"} 5
+opa_openshift_decision_reviews_total{permission="read",tenant="unknown"} 0
+`), "opa_openshift_decisions_total", "opa_openshift_decision_reviews_total"))
+
+	require.Equal(t, 4, testutil.CollectAndCount(reg, "opa_openshift_decision_duration_seconds", "opa_openshift_decision_namespaces"))
+}
+
+func TestNew_LabelAliases(t *testing.T) {
+	c := &openshiftfakes.FakeClient{}
+	c.AccessReviewReturns(true, nil)
+
+	cfg := &config.Config{
+		Mappings: map[string]string{"application": "loki.grafana.com"},
+		Opa: config.OPAConfig{
+			Matcher:             "kubernetes_namespace_name,k8s_namespace_name",
+			MatcherOp:           string(config.MatcherOr),
+			MatcherAdminGroups:  "cluster-admin",
+			ViaQToOTELMigration: true,
+		},
+	}
+
+	h := New(log.NewNopLogger(), cache.NewInMemoryCache(60, 0, 0), &fakeClientProvider{client: c}, cfg)
+
+	tt := []struct {
+		desc      string
+		groups    string
+		selectors string
+		wantCode  int
+		wantBody  string
+	}{
+		{
+			desc:      "ViaQ selectors",
+			selectors: `{"kubernetes_namespace_name":["ns-a"]}`,
+			wantCode:  http.StatusOK,
+			wantBody:  `\"Name\":\"kubernetes_namespace_name\"`,
+		},
+		{
+			desc:      "OTel selectors",
+			selectors: `{"k8s_namespace_name":["ns-a"]}`,
+			wantCode:  http.StatusOK,
+			wantBody:  `\"Name\":\"k8s_namespace_name\"`,
+		},
+		{
+			desc:      "mixed selectors",
+			selectors: `{"kubernetes_namespace_name":["ns-a"],"k8s_namespace_name":["ns-a"]}`,
+			wantCode:  http.StatusBadRequest,
+			wantBody:  "queries with both 'kubernetes_namespace_name' and 'k8s_namespace_name' selectors are not allowed",
+		},
+		{
+			desc:      "mixed selectors of admin",
+			groups:    `["cluster-admin"]`,
+			selectors: `{"kubernetes_namespace_name":["ns-a"],"k8s_namespace_name":["ns-a"]}`,
+			wantCode:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			groups := tc.groups
+			if groups == "" {
+				groups = "[]"
+			}
+
+			body := `{"input":{"subject":"user","groups":` + groups + `,"permission":"read","resource":"logs","tenant":"application","extras":{"selectors":` + tc.selectors + `}}}`
+			req := httptest.NewRequest(http.MethodPost, "/v1/data/observatorium/allow", strings.NewReader(body))
+			req.Header.Set(xForwardedAccessTokenHeader, "test-token")
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.wantCode, rec.Code)
+			require.Contains(t, rec.Body.String(), tc.wantBody)
+		})
+	}
+}
+
+func TestNew_TenantSettings(t *testing.T) {
+	c := &openshiftfakes.FakeClient{}
+	c.AccessReviewReturns(true, nil)
+
+	cfg := &config.Config{
+		Mappings: map[string]string{"application": "loki.grafana.com"},
+		Tenants: map[string]config.Tenant{
+			"application": {
+				Name:      "application",
+				APIGroup:  "loki.grafana.com",
+				Resources: map[string]string{"logs": "application-logs"},
+				SSAR:      true,
+			},
+		},
+	}
+
+	cp := &fakeClientProvider{client: c}
+	h := New(log.NewNopLogger(), cache.NewInMemoryCache(60, 0, 0), cp, cfg)
+
+	body := `{"input":{"subject":"user","permission":"read","resource":"logs","tenant":"application"}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/data/observatorium/allow", strings.NewReader(body))
+	req.Header.Set(xForwardedAccessTokenHeader, "test-token")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, cp.ssar)
+	require.Equal(t, 1, c.AccessReviewCallCount())
+
+	_, _, _, _, _, resourceName, apiGroup, _ := c.AccessReviewArgsForCall(0)
+	require.Equal(t, "application-logs", resourceName)
+	require.Equal(t, "loki.grafana.com", apiGroup)
+}
+
+func TestNew_WithConfig(t *testing.T) {
+	c := &openshiftfakes.FakeClient{}
+	c.AccessReviewReturns(true, nil)
+
+	var current atomic.Pointer[config.Config]
+
+	current.Store(&config.Config{Mappings: map[string]string{}})
+
+	h := New(log.NewNopLogger(), cache.NewInMemoryCache(60, 0, 0), &fakeClientProvider{client: c}, current.Load(),
+		WithConfig(current.Load),
+	)
+
+	do := func() int {
+		body := `{"input":{"subject":"user","permission":"read","resource":"logs","tenant":"application"}}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/data/observatorium/allow", strings.NewReader(body))
+		req.Header.Set(xForwardedAccessTokenHeader, "test-token")
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusInternalServerError, do())
+
+	// Tenants added to the configuration are picked up by the following requests.
+	current.Store(&config.Config{Mappings: map[string]string{"application": "loki.grafana.com"}})
+	require.Equal(t, http.StatusOK, do())
+}
+
+func TestNew_RulesReviewOnlyWithSSAR(t *testing.T) {
+	tt := []struct {
+		desc            string
+		ssar            bool
+		wantRulesReview int
+	}{
+		{desc: "sar", ssar: false, wantRulesReview: 0},
+		{desc: "ssar", ssar: true, wantRulesReview: 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := &openshiftfakes.FakeClient{}
+			c.RulesReviewReturns(nil, true, nil)
+
+			// The debug token belongs to another subject than the one of the request.
+			cfg := &config.Config{
+				DebugToken: "debug-token",
+				Mappings:   map[string]string{"application": "loki.grafana.com"},
+				Opa:        config.OPAConfig{Matcher: "kubernetes_namespace_name", SSRR: true},
+				Tenants: map[string]config.Tenant{
+					"application": {Name: "application", APIGroup: "loki.grafana.com", SSAR: tc.ssar, SSRR: true},
+				},
+			}
+
+			h := New(log.NewNopLogger(), cache.NewInMemoryCache(60, 0, 0), &fakeClientProvider{client: c}, cfg)
+
+			body := `{"input":{"subject":"alice","permission":"read","resource":"logs","tenant":"application","extras":{"selectors":{"kubernetes_namespace_name":["ns-a"]}}}}`
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/data/observatorium/allow", strings.NewReader(body)))
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Equal(t, tc.wantRulesReview, c.RulesReviewCallCount())
+
+			// The namespace is reviewed for the subject of the request.
+			require.Equal(t, 2, c.AccessReviewCallCount())
+
+			_, user, _, _, _, _, _, namespace := c.AccessReviewArgsForCall(1)
+			require.Equal(t, "alice", user)
+			require.Equal(t, "ns-a", namespace)
+		})
+	}
+}
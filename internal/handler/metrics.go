@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const unknownLabelValue = "unknown"
+
+// DecisionMetrics counts authorization decisions by outcome and observes their cost.
+type DecisionMetrics struct {
+	decisions  *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	namespaces *prometheus.HistogramVec
+	reviews    *prometheus.CounterVec
+}
+
+func NewDecisionMetrics(r prometheus.Registerer) *DecisionMetrics {
+	m := &DecisionMetrics{
+		decisions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "opa_openshift_decisions_total",
+				Help: "Counts the number of authorization decisions by tenant, permission and result.",
+			},
+			[]string{"tenant", "permission", "result"},
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "opa_openshift_decision_duration_seconds",
+				Help:    "A histogram of authorization decision latencies.",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"tenant", "permission"},
+		),
+		namespaces: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "opa_openshift_decision_namespaces",
+				Help:    "A histogram of the number of namespaces requested per authorization decision.",
+				Buckets: prometheus.ExponentialBuckets(1, 4, 7), //nolint:gomnd
+			},
+			[]string{"tenant", "permission"},
+		),
+		reviews: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "opa_openshift_decision_reviews_total",
+				Help: "Counts the number of access and rules reviews issued to the API server for authorization decisions.",
+			},
+			[]string{"tenant", "permission"},
+		),
+	}
+
+	if r != nil {
+		r.MustRegister(m.decisions, m.duration, m.namespaces, m.reviews)
+	}
+
+	return m
+}
+
+// observe records a single decision. It is a no-op on nil DecisionMetrics.
+func (m *DecisionMetrics) observe(tenant, permission, result string, latency time.Duration, namespaces, reviews int) {
+	if m == nil {
+		return
+	}
+
+	m.decisions.WithLabelValues(tenant, permission, result).Inc()
+	m.duration.WithLabelValues(tenant, permission).Observe(latency.Seconds())
+	m.namespaces.WithLabelValues(tenant, permission).Observe(float64(namespaces))
+	m.reviews.WithLabelValues(tenant, permission).Add(float64(reviews))
+}
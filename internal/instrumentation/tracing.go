@@ -0,0 +1,65 @@
+package instrumentation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// propagator reads and writes W3C trace context headers.
+var propagator = propagation.TraceContext{} //nolint:gochecknoglobals
+
+// NewTracerProvider returns a TracerProvider exporting spans to the OTLP/HTTP endpoint,
+// e.g. "otel-collector:4318". Traces are sampled with the given ratio unless the
+// calling service already decided to sample them.
+func NewTracerProvider(ctx context.Context, serviceName, endpoint string, insecure bool, ratio float64) (*sdktrace.TracerProvider, error) {
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res := resource.NewSchemaless(attribute.String("service.name", serviceName))
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	), nil
+}
+
+// NewTracingHandler wraps a HTTP handler with a server span, continuing the trace
+// of the caller when it propagates a W3C trace context.
+func NewTracingHandler(tp trace.TracerProvider, operation string, h http.Handler) http.Handler {
+	return otelhttp.NewHandler(h, operation,
+		otelhttp.WithTracerProvider(tp),
+		otelhttp.WithPropagators(propagator),
+		otelhttp.WithSpanNameFormatter(func(operation string, _ *http.Request) string {
+			return operation
+		}),
+	)
+}
+
+// NewTracingRoundTripper wraps a HTTP RoundTripper with a client span per request,
+// named after the client, the method and the path of the request.
+func NewTracingRoundTripper(tp trace.TracerProvider, name string, rt http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(rt,
+		otelhttp.WithTracerProvider(tp),
+		otelhttp.WithPropagators(propagator),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return fmt.Sprintf("%s %s %s", name, r.Method, r.URL.Path)
+		}),
+	)
+}
@@ -0,0 +1,61 @@
+package instrumentation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	callerTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	callerSpanID  = "00f067aa0ba902b7"
+)
+
+func TestTracing_PropagatesTraceContext(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+
+	var upstreamTraceparent string
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: NewTracingRoundTripper(tp, "openshift", http.DefaultTransport)}
+
+	h := NewTracingHandler(tp, "data", http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, upstream.URL+"/apis/authorization.k8s.io/v1/subjectaccessreviews", nil)
+		require.NoError(t, err)
+
+		res, err := client.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/data/observatorium/allow", nil)
+	req.Header.Set("traceparent", "00-"+callerTraceID+"-"+callerSpanID+"-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exp.GetSpans()
+	require.Len(t, spans, 2)
+
+	client0, server := spans[0], spans[1]
+	require.Equal(t, "openshift POST /apis/authorization.k8s.io/v1/subjectaccessreviews", client0.Name)
+	require.Equal(t, trace.SpanKindClient, client0.SpanKind)
+	require.Equal(t, "data", server.Name)
+	require.Equal(t, trace.SpanKindServer, server.SpanKind)
+
+	// The server span continues the trace of the caller, the client span is its child.
+	require.Equal(t, callerTraceID, server.SpanContext.TraceID().String())
+	require.Equal(t, callerSpanID, server.Parent.SpanID().String())
+	require.Equal(t, server.SpanContext.SpanID(), client0.Parent.SpanID())
+
+	// The trace context is propagated to the API server.
+	require.Equal(t, "00-"+callerTraceID+"-"+client0.SpanContext.SpanID().String()+"-01", upstreamTraceparent)
+}
@@ -2,6 +2,7 @@ package openshift
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/user"
@@ -9,83 +10,52 @@ import (
 
 	"github.com/observatorium/opa-openshift/internal/external/k8s"
 	"github.com/observatorium/opa-openshift/internal/external/ocp"
-	projectv1 "github.com/openshift/client-go/project/clientset/versioned/typed/project/v1"
 	authorizationv1 "k8s.io/api/authorization/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/transport"
 )
 
+// errRulesReviewWithoutSSAR is returned for rules reviews of clients issuing SubjectAccessReviews,
+// as the rules of the holder of the token may not be the ones of the subject of the reviews.
+var errRulesReviewWithoutSSAR = errors.New("self subject rules reviews require self subject access reviews")
+
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
+
 // Client is the standard openshift client to
 // check authentication and authorization for
 // subjects.
+//
+//counterfeiter:generate . Client
 type Client interface {
-	AccessReview(user string, groups []string, verb, resource, resourceName, apiGroup, namespace string) (bool, error)
-	ListNamespaces() ([]string, error)
+	AccessReview(ctx context.Context, user string, groups []string, verb, resource, resourceName, apiGroup, namespace string) (bool, error)
+	RulesReview(ctx context.Context, namespace string) ([]authorizationv1.ResourceRule, bool, error)
+	ListNamespaces(ctx context.Context) ([]string, error)
 }
 
 type client struct {
 	k8sClient     k8s.ClientSet
 	projectClient ocp.ProjectV1Client
+	// selfClient acts with the subject's token and is used to issue
+	// SelfSubjectRulesReviews. It is only set along ssar.
+	selfClient k8s.ClientSet
 	// SSAR is true if the client will issue SelfSubjectAccessReview instead of
 	// SubjectAccessReview.
 	ssar bool
 }
 
-// NewClient returns a new OpenShift client holding a pointer to a k8s clientset
-// and a pointer to the OpenShift project clientset. Both clientset require a
-// kube config file on a prescribed path or under $HOME/.kube. The loaded kube
-// configuration is sanitized and augmented with the subject's forwarded bearer
-// token.
-func NewClient(wt transport.WrapperFunc, kubeconfigPath, token string, ssar bool) (Client, error) {
-	cfg, err := getConfig(kubeconfigPath)
-	if err != nil {
-		return nil, err
-	}
-
-	if ssar {
-		cfg = rest.AnonymousClientConfig(cfg)
-		cfg.BearerToken = token
-	}
-	cfg.WrapTransport = wt
-
-	clientset, err := kubernetes.NewForConfig(cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create k8s clientset: %w", err)
-	}
-
-	// Set user token to acces the project clienset
-	// to request only user-accessible projects.
-	cfg = rest.AnonymousClientConfig(cfg)
-	cfg.BearerToken = token
-	cfg.WrapTransport = wt
-
-	projectClient, err := projectv1.NewForConfig(cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create ocp project clientset: %w", err)
-	}
-
-	return &client{
-		k8sClient:     clientset,
-		projectClient: projectClient,
-		ssar:          ssar,
-	}, nil
-}
-
 // SubjectAccessReview requests a subject access review from the k8s api server
 // for an authenticated user.
-func (c *client) AccessReview(user string, groups []string, verb, resource, resourceName, apiGroup, namespace string) (bool, error) {
+func (c *client) AccessReview(ctx context.Context, user string, groups []string, verb, resource, resourceName, apiGroup, namespace string) (bool, error) {
 	if c.ssar {
-		return c.selfSubjectAccessReview(verb, resource, resourceName, apiGroup, namespace)
+		return c.selfSubjectAccessReview(ctx, verb, resource, resourceName, apiGroup, namespace)
 	}
-	return c.subjectAccessReview(user, groups, verb, resource, resourceName, apiGroup, namespace)
+	return c.subjectAccessReview(ctx, user, groups, verb, resource, resourceName, apiGroup, namespace)
 }
 
 // SubjectAccessReview requests a subject access review from the k8s api server
 // for an authenticated user.
-func (c *client) subjectAccessReview(user string, groups []string, verb, resource, resourceName, apiGroup, namespace string) (bool, error) {
+func (c *client) subjectAccessReview(ctx context.Context, user string, groups []string, verb, resource, resourceName, apiGroup, namespace string) (bool, error) {
 	ssar := &authorizationv1.SubjectAccessReview{
 		Spec: authorizationv1.SubjectAccessReviewSpec{
 			User:   user,
@@ -100,7 +70,7 @@ func (c *client) subjectAccessReview(user string, groups []string, verb, resourc
 		},
 	}
 
-	res, err := c.k8sClient.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), ssar, metav1.CreateOptions{})
+	res, err := c.k8sClient.AuthorizationV1().SubjectAccessReviews().Create(ctx, ssar, metav1.CreateOptions{})
 	if err != nil {
 		return false, fmt.Errorf("failed to create subject access review: %w", err)
 	}
@@ -110,7 +80,7 @@ func (c *client) subjectAccessReview(user string, groups []string, verb, resourc
 
 // SelfSubjectAccessReview requests a self subject access review from the k8s api server
 // for an authenticated user.
-func (c *client) selfSubjectAccessReview(verb, resource, resourceName, apiGroup, namespace string) (bool, error) {
+func (c *client) selfSubjectAccessReview(ctx context.Context, verb, resource, resourceName, apiGroup, namespace string) (bool, error) {
 	ssar := &authorizationv1.SelfSubjectAccessReview{
 		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
 			ResourceAttributes: &authorizationv1.ResourceAttributes{
@@ -123,7 +93,7 @@ func (c *client) selfSubjectAccessReview(verb, resource, resourceName, apiGroup,
 		},
 	}
 
-	res, err := c.k8sClient.AuthorizationV1().SelfSubjectAccessReviews().Create(context.TODO(), ssar, metav1.CreateOptions{})
+	res, err := c.k8sClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, ssar, metav1.CreateOptions{})
 	if err != nil {
 		return false, fmt.Errorf("failed to create self subject access review: %w", err)
 	}
@@ -131,10 +101,32 @@ func (c *client) selfSubjectAccessReview(verb, resource, resourceName, apiGroup,
 	return res.Status.Allowed, nil
 }
 
+// RulesReview requests a self subject rules review from the k8s api server
+// and returns the resource rules of the authenticated user in the given
+// namespace, as well as whether the rule set is incomplete.
+func (c *client) RulesReview(ctx context.Context, namespace string) ([]authorizationv1.ResourceRule, bool, error) {
+	if !c.ssar {
+		return nil, false, errRulesReviewWithoutSSAR
+	}
+
+	ssrr := &authorizationv1.SelfSubjectRulesReview{
+		Spec: authorizationv1.SelfSubjectRulesReviewSpec{
+			Namespace: namespace,
+		},
+	}
+
+	res, err := c.selfClient.AuthorizationV1().SelfSubjectRulesReviews().Create(ctx, ssrr, metav1.CreateOptions{})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create self subject rules review: %w", err)
+	}
+
+	return res.Status.ResourceRules, res.Status.Incomplete, nil
+}
+
 // ListNamespaces provides a list of all namespaces an authenticated user
 // has access to or an error on failure.
-func (c *client) ListNamespaces() ([]string, error) {
-	projects, err := c.projectClient.Projects().List(context.TODO(), metav1.ListOptions{})
+func (c *client) ListNamespaces(ctx context.Context) ([]string, error) {
+	projects, err := c.projectClient.Projects().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list projects: %w", err)
 	}
@@ -40,7 +40,7 @@ func TestListNamespaces_ReturnsOnlyNames(t *testing.T) {
 		projectClient: projectsClient,
 	}
 
-	got, err := c.ListNamespaces()
+	got, err := c.ListNamespaces(context.Background())
 	require.NoError(t, err)
 
 	want := []string{"ns1", "ns2"}
@@ -88,7 +88,7 @@ func TestSubjectAccessReview_HandleResourceAttributesOnly(t *testing.T) {
 		return sar, nil
 	})
 
-	_, err := c.AccessReview(input.user, input.groups, input.verb, input.resource, input.resourceName, input.apiGroup, "")
+	_, err := c.AccessReview(context.Background(), input.user, input.groups, input.verb, input.resource, input.resourceName, input.apiGroup, "")
 	require.NoError(t, err)
 }
 
@@ -131,6 +131,37 @@ func TestSefSubjectAccessReview_HandleResourceAttributesOnly(t *testing.T) {
 		return ssar, nil
 	})
 
-	_, err := c.AccessReview(input.user, input.groups, input.verb, input.resource, input.resourceName, input.apiGroup, "")
+	_, err := c.AccessReview(context.Background(), input.user, input.groups, input.verb, input.resource, input.resourceName, input.apiGroup, "")
 	require.NoError(t, err)
 }
+
+func TestSelfSubjectRulesReview_ReturnsResourceRules(t *testing.T) {
+	authzv1 := &k8sfakes.FakeAuthorizationV1Interface{}
+	ssrr := &k8sfakes.FakeSelfSubjectRulesReviewInterface{}
+	k8sClient := &k8sfakes.FakeClientSet{}
+
+	authzv1.SelfSubjectRulesReviewsReturns(ssrr)
+	k8sClient.AuthorizationV1Returns(authzv1)
+
+	c := client{selfClient: k8sClient, ssar: true}
+
+	rules := []authorizationv1.ResourceRule{
+		{Verbs: []string{"get"}, APIGroups: []string{"group.me.io"}, Resources: []string{"tenantID"}},
+	}
+
+	ssrr.CreateCalls(func(_ context.Context, ssrr *authorizationv1.SelfSubjectRulesReview, _ metav1.CreateOptions) (*authorizationv1.SelfSubjectRulesReview, error) { //nolint:lll
+		require.Equal(t, "ns1", ssrr.Spec.Namespace)
+		ssrr.Status = authorizationv1.SubjectRulesReviewStatus{ResourceRules: rules, Incomplete: true}
+
+		return ssrr, nil
+	})
+
+	got, incomplete, err := c.RulesReview(context.Background(), "ns1")
+	require.NoError(t, err)
+	require.True(t, incomplete)
+	require.Equal(t, rules, got)
+
+	// The rules of the holder of the token must not answer reviews of another subject.
+	_, _, err = (&client{k8sClient: k8sClient}).RulesReview(context.Background(), "ns1")
+	require.ErrorIs(t, err, errRulesReviewWithoutSSAR)
+}
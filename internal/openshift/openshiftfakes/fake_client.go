@@ -0,0 +1,296 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package openshiftfakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/observatorium/opa-openshift/internal/openshift"
+	v1 "k8s.io/api/authorization/v1"
+)
+
+type FakeClient struct {
+	AccessReviewStub        func(context.Context, string, []string, string, string, string, string, string) (bool, error)
+	accessReviewMutex       sync.RWMutex
+	accessReviewArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 []string
+		arg4 string
+		arg5 string
+		arg6 string
+		arg7 string
+		arg8 string
+	}
+	accessReviewReturns struct {
+		result1 bool
+		result2 error
+	}
+	accessReviewReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
+	ListNamespacesStub        func(context.Context) ([]string, error)
+	listNamespacesMutex       sync.RWMutex
+	listNamespacesArgsForCall []struct {
+		arg1 context.Context
+	}
+	listNamespacesReturns struct {
+		result1 []string
+		result2 error
+	}
+	listNamespacesReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	RulesReviewStub        func(context.Context, string) ([]v1.ResourceRule, bool, error)
+	rulesReviewMutex       sync.RWMutex
+	rulesReviewArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	rulesReviewReturns struct {
+		result1 []v1.ResourceRule
+		result2 bool
+		result3 error
+	}
+	rulesReviewReturnsOnCall map[int]struct {
+		result1 []v1.ResourceRule
+		result2 bool
+		result3 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeClient) AccessReview(arg1 context.Context, arg2 string, arg3 []string, arg4 string, arg5 string, arg6 string, arg7 string, arg8 string) (bool, error) {
+	var arg3Copy []string
+	if arg3 != nil {
+		arg3Copy = make([]string, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	fake.accessReviewMutex.Lock()
+	ret, specificReturn := fake.accessReviewReturnsOnCall[len(fake.accessReviewArgsForCall)]
+	fake.accessReviewArgsForCall = append(fake.accessReviewArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 []string
+		arg4 string
+		arg5 string
+		arg6 string
+		arg7 string
+		arg8 string
+	}{arg1, arg2, arg3Copy, arg4, arg5, arg6, arg7, arg8})
+	stub := fake.AccessReviewStub
+	fakeReturns := fake.accessReviewReturns
+	fake.recordInvocation("AccessReview", []interface{}{arg1, arg2, arg3Copy, arg4, arg5, arg6, arg7, arg8})
+	fake.accessReviewMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeClient) AccessReviewCallCount() int {
+	fake.accessReviewMutex.RLock()
+	defer fake.accessReviewMutex.RUnlock()
+	return len(fake.accessReviewArgsForCall)
+}
+
+func (fake *FakeClient) AccessReviewCalls(stub func(context.Context, string, []string, string, string, string, string, string) (bool, error)) {
+	fake.accessReviewMutex.Lock()
+	defer fake.accessReviewMutex.Unlock()
+	fake.AccessReviewStub = stub
+}
+
+func (fake *FakeClient) AccessReviewArgsForCall(i int) (context.Context, string, []string, string, string, string, string, string) {
+	fake.accessReviewMutex.RLock()
+	defer fake.accessReviewMutex.RUnlock()
+	argsForCall := fake.accessReviewArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6, argsForCall.arg7, argsForCall.arg8
+}
+
+func (fake *FakeClient) AccessReviewReturns(result1 bool, result2 error) {
+	fake.accessReviewMutex.Lock()
+	defer fake.accessReviewMutex.Unlock()
+	fake.AccessReviewStub = nil
+	fake.accessReviewReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) AccessReviewReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.accessReviewMutex.Lock()
+	defer fake.accessReviewMutex.Unlock()
+	fake.AccessReviewStub = nil
+	if fake.accessReviewReturnsOnCall == nil {
+		fake.accessReviewReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.accessReviewReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) ListNamespaces(arg1 context.Context) ([]string, error) {
+	fake.listNamespacesMutex.Lock()
+	ret, specificReturn := fake.listNamespacesReturnsOnCall[len(fake.listNamespacesArgsForCall)]
+	fake.listNamespacesArgsForCall = append(fake.listNamespacesArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.ListNamespacesStub
+	fakeReturns := fake.listNamespacesReturns
+	fake.recordInvocation("ListNamespaces", []interface{}{arg1})
+	fake.listNamespacesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeClient) ListNamespacesCallCount() int {
+	fake.listNamespacesMutex.RLock()
+	defer fake.listNamespacesMutex.RUnlock()
+	return len(fake.listNamespacesArgsForCall)
+}
+
+func (fake *FakeClient) ListNamespacesCalls(stub func(context.Context) ([]string, error)) {
+	fake.listNamespacesMutex.Lock()
+	defer fake.listNamespacesMutex.Unlock()
+	fake.ListNamespacesStub = stub
+}
+
+func (fake *FakeClient) ListNamespacesArgsForCall(i int) context.Context {
+	fake.listNamespacesMutex.RLock()
+	defer fake.listNamespacesMutex.RUnlock()
+	argsForCall := fake.listNamespacesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeClient) ListNamespacesReturns(result1 []string, result2 error) {
+	fake.listNamespacesMutex.Lock()
+	defer fake.listNamespacesMutex.Unlock()
+	fake.ListNamespacesStub = nil
+	fake.listNamespacesReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) ListNamespacesReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.listNamespacesMutex.Lock()
+	defer fake.listNamespacesMutex.Unlock()
+	fake.ListNamespacesStub = nil
+	if fake.listNamespacesReturnsOnCall == nil {
+		fake.listNamespacesReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.listNamespacesReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) RulesReview(arg1 context.Context, arg2 string) ([]v1.ResourceRule, bool, error) {
+	fake.rulesReviewMutex.Lock()
+	ret, specificReturn := fake.rulesReviewReturnsOnCall[len(fake.rulesReviewArgsForCall)]
+	fake.rulesReviewArgsForCall = append(fake.rulesReviewArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.RulesReviewStub
+	fakeReturns := fake.rulesReviewReturns
+	fake.recordInvocation("RulesReview", []interface{}{arg1, arg2})
+	fake.rulesReviewMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2, ret.result3
+	}
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
+}
+
+func (fake *FakeClient) RulesReviewCallCount() int {
+	fake.rulesReviewMutex.RLock()
+	defer fake.rulesReviewMutex.RUnlock()
+	return len(fake.rulesReviewArgsForCall)
+}
+
+func (fake *FakeClient) RulesReviewCalls(stub func(context.Context, string) ([]v1.ResourceRule, bool, error)) {
+	fake.rulesReviewMutex.Lock()
+	defer fake.rulesReviewMutex.Unlock()
+	fake.RulesReviewStub = stub
+}
+
+func (fake *FakeClient) RulesReviewArgsForCall(i int) (context.Context, string) {
+	fake.rulesReviewMutex.RLock()
+	defer fake.rulesReviewMutex.RUnlock()
+	argsForCall := fake.rulesReviewArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeClient) RulesReviewReturns(result1 []v1.ResourceRule, result2 bool, result3 error) {
+	fake.rulesReviewMutex.Lock()
+	defer fake.rulesReviewMutex.Unlock()
+	fake.RulesReviewStub = nil
+	fake.rulesReviewReturns = struct {
+		result1 []v1.ResourceRule
+		result2 bool
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeClient) RulesReviewReturnsOnCall(i int, result1 []v1.ResourceRule, result2 bool, result3 error) {
+	fake.rulesReviewMutex.Lock()
+	defer fake.rulesReviewMutex.Unlock()
+	fake.RulesReviewStub = nil
+	if fake.rulesReviewReturnsOnCall == nil {
+		fake.rulesReviewReturnsOnCall = make(map[int]struct {
+			result1 []v1.ResourceRule
+			result2 bool
+			result3 error
+		})
+	}
+	fake.rulesReviewReturnsOnCall[i] = struct {
+		result1 []v1.ResourceRule
+		result2 bool
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeClient) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeClient) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ openshift.Client = new(FakeClient)
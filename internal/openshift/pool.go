@@ -0,0 +1,178 @@
+package openshift
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+	"github.com/observatorium/opa-openshift/internal/external/k8s"
+	projectv1 "github.com/openshift/client-go/project/clientset/versioned/typed/project/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+)
+
+const (
+	poolMetricsPrefix = "opa_openshift_client_pool_"
+
+	poolRequestResultHit  = "hit"
+	poolRequestResultMiss = "miss"
+)
+
+var errInvalidPoolSize = errors.New("client pool size must be positive")
+
+var (
+	descPoolSize = prometheus.NewDesc(
+		poolMetricsPrefix+"size",
+		"Number of per-token clients currently held in the pool.",
+		nil, nil)
+	descPoolRequests = prometheus.NewDesc(
+		poolMetricsPrefix+"requests_total",
+		"Counts the number of client lookups in the pool.",
+		[]string{"result"}, nil)
+	descPoolEvictions = prometheus.NewDesc(
+		poolMetricsPrefix+"evictions_total",
+		"Counts the number of clients evicted from the pool.",
+		nil, nil)
+)
+
+// ClientProvider hands out OpenShift clients acting on behalf of
+// the subject owning the given bearer token. When ssar is set, the
+// clients issue SelfSubjectAccessReviews instead of SubjectAccessReviews.
+type ClientProvider interface {
+	ForToken(token string, ssar bool) (Client, error)
+}
+
+// ClientPool is a ClientProvider reusing clientsets across requests. The
+// SubjectAccessReview clientset is built once and shared, while clients
+// bound to a subject's token are kept in a size-bounded LRU with TTL.
+type ClientPool struct {
+	cfg       *rest.Config
+	wt        transport.WrapperFunc
+	k8sClient k8s.ClientSet
+	clients   *ttlcache.Cache[string, *client]
+}
+
+// NewClientPool loads the kube config once and returns a pool of OpenShift
+// clients holding at most size per-token clients, each expiring after ttl
+// without use.
+func NewClientPool(wt transport.WrapperFunc, kubeconfigPath string, size int, ttl time.Duration) (*ClientPool, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("%w: %d", errInvalidPoolSize, size)
+	}
+
+	cfg, err := getConfig(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ClientPool{
+		cfg: cfg,
+		wt:  wt,
+		clients: ttlcache.New(
+			ttlcache.WithTTL[string, *client](ttl),
+			ttlcache.WithCapacity[string, *client](uint64(size)),
+		),
+	}
+
+	saCfg := rest.CopyConfig(cfg)
+	saCfg.WrapTransport = wt
+
+	p.k8sClient, err = kubernetes.NewForConfig(saCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s clientset: %w", err)
+	}
+
+	return p, nil
+}
+
+// ForToken returns a pooled client for the given token and access review mode,
+// creating it on a miss.
+func (p *ClientPool) ForToken(token string, ssar bool) (Client, error) {
+	key := hashToken(token)
+	if ssar {
+		key += ":ssar"
+	}
+
+	if item := p.clients.Get(key); item != nil {
+		return item.Value(), nil
+	}
+
+	c, err := p.newClient(token, ssar)
+	if err != nil {
+		return nil, err
+	}
+
+	p.clients.Set(key, c, ttlcache.DefaultTTL)
+
+	return c, nil
+}
+
+func (p *ClientPool) newClient(token string, ssar bool) (*client, error) {
+	// Set user token to access the project clientset
+	// to request only user-accessible projects.
+	cfg := rest.AnonymousClientConfig(p.cfg)
+	cfg.BearerToken = token
+	cfg.WrapTransport = p.wt
+
+	projectClient, err := projectv1.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ocp project clientset: %w", err)
+	}
+
+	// Self subject access and rules reviews are issued on behalf of the subject.
+	var selfClient k8s.ClientSet
+	if ssar {
+		selfClient, err = kubernetes.NewForConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create k8s clientset: %w", err)
+		}
+	}
+
+	k8sClient := p.k8sClient
+	if ssar {
+		k8sClient = selfClient
+	}
+
+	return &client{
+		k8sClient:     k8sClient,
+		projectClient: projectClient,
+		selfClient:    selfClient,
+		ssar:          ssar,
+	}, nil
+}
+
+func (p *ClientPool) Describe(descs chan<- *prometheus.Desc) {
+	descs <- descPoolSize
+	descs <- descPoolRequests
+	descs <- descPoolEvictions
+}
+
+func (p *ClientPool) Collect(metricsCh chan<- prometheus.Metric) {
+	metrics := p.clients.Metrics()
+
+	metricsCh <- prometheus.MustNewConstMetric(descPoolSize, prometheus.GaugeValue, float64(p.clients.Len()))
+	metricsCh <- prometheus.MustNewConstMetric(descPoolRequests,
+		prometheus.CounterValue, float64(metrics.Hits), poolRequestResultHit)
+	metricsCh <- prometheus.MustNewConstMetric(descPoolRequests,
+		prometheus.CounterValue, float64(metrics.Misses), poolRequestResultMiss)
+	metricsCh <- prometheus.MustNewConstMetric(descPoolEvictions, prometheus.CounterValue, float64(metrics.Evictions))
+}
+
+// Start runs the periodic removal of expired clients until Stop is called.
+func (p *ClientPool) Start() {
+	p.clients.Start()
+}
+
+// Stop halts the removal of expired clients.
+func (p *ClientPool) Stop() {
+	p.clients.Stop()
+}
+
+// hashToken avoids keeping bearer tokens around in clear as pool keys.
+func hashToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
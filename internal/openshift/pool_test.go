@@ -0,0 +1,102 @@
+package openshift
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    token: service-account-token
+`
+
+func TestClientPool_ReusesClientsPerToken(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(testKubeconfig), 0o600))
+
+	p, err := NewClientPool(nil, kubeconfig, 1, time.Minute)
+	require.NoError(t, err)
+
+	a1, err := p.ForToken("token-a", false)
+	require.NoError(t, err)
+
+	a2, err := p.ForToken("token-a", false)
+	require.NoError(t, err)
+	require.Same(t, a1, a2)
+
+	b, err := p.ForToken("token-b", false)
+	require.NoError(t, err)
+	require.NotSame(t, a1, b)
+
+	// The SAR clientset is shared across all subjects.
+	require.Same(t, a1.(*client).k8sClient, b.(*client).k8sClient)
+
+	// The pool is bounded, so token-a was evicted in favor of token-b.
+	a3, err := p.ForToken("token-a", false)
+	require.NoError(t, err)
+	require.NotSame(t, a1, a3)
+
+	want := `
+# HELP opa_openshift_client_pool_requests_total Counts the number of client lookups in the pool.
+# TYPE opa_openshift_client_pool_requests_total counter
+opa_openshift_client_pool_requests_total{result="hit"} 1
+opa_openshift_client_pool_requests_total{result="miss"} 3
+# HELP opa_openshift_client_pool_size Number of per-token clients currently held in the pool.
+# TYPE opa_openshift_client_pool_size gauge
+opa_openshift_client_pool_size 1
+`
+	require.NoError(t, testutil.CollectAndCompare(p, strings.NewReader(want),
+		poolMetricsPrefix+"requests_total", poolMetricsPrefix+"size"))
+}
+
+func TestClientPool_SSARUsesPerTokenClientset(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(testKubeconfig), 0o600))
+
+	p, err := NewClientPool(nil, kubeconfig, 10, time.Minute)
+	require.NoError(t, err)
+
+	a, err := p.ForToken("token-a", true)
+	require.NoError(t, err)
+
+	b, err := p.ForToken("token-b", true)
+	require.NoError(t, err)
+
+	require.NotSame(t, a.(*client).k8sClient, b.(*client).k8sClient)
+
+	// The same token is pooled separately for SubjectAccessReviews.
+	sar, err := p.ForToken("token-a", false)
+	require.NoError(t, err)
+	require.NotSame(t, a, sar)
+	require.True(t, a.(*client).ssar)
+	require.False(t, sar.(*client).ssar)
+}
+
+func TestClientPool_InvalidSize(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(testKubeconfig), 0o600))
+
+	for _, size := range []int{0, -1} {
+		_, err := NewClientPool(nil, kubeconfig, size, time.Minute)
+		require.ErrorIs(t, err, errInvalidPoolSize)
+	}
+}
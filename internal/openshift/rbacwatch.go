@@ -0,0 +1,229 @@
+package openshift
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	projectclientset "github.com/openshift/client-go/project/clientset/versioned"
+	projectinformers "github.com/openshift/client-go/project/informers/externalversions"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/transport"
+)
+
+const resourceProjects = "projects"
+
+// RBACWatcher versions authorization decisions by the RBAC objects they depend on.
+// It watches Roles, RoleBindings, ClusterRoles, ClusterRoleBindings and Projects
+// and maintains a digest of the resource versions of the cluster-scoped objects
+// and of the namespaced objects and the Project of every namespace. Since the
+// digests only depend on the watched objects, all replicas sharing a cache agree
+// on them. Until the initial list of all objects completed, no generations are
+// returned, so that decisions are not cached under partial digests.
+type RBACWatcher struct {
+	kubeInformers    informers.SharedInformerFactory
+	projectInformers projectinformers.SharedInformerFactory
+	synced           []cache.InformerSynced
+	ready            atomic.Bool
+
+	mu            sync.RWMutex
+	cluster       uint64
+	namespaces    map[string]uint64
+	allNamespaces uint64
+
+	changes *prometheus.CounterVec
+}
+
+// NewRBACWatcher returns an RBACWatcher using the service account of the kube config.
+func NewRBACWatcher(wt transport.WrapperFunc, kubeconfigPath string, r prometheus.Registerer) (*RBACWatcher, error) {
+	cfg, err := getConfig(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg = rest.CopyConfig(cfg)
+	cfg.WrapTransport = wt
+
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s clientset: %w", err)
+	}
+
+	projectClient, err := projectclientset.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ocp project clientset: %w", err)
+	}
+
+	return newRBACWatcher(kubeClient, projectClient, r)
+}
+
+func newRBACWatcher(kc kubernetes.Interface, pc projectclientset.Interface, r prometheus.Registerer) (*RBACWatcher, error) {
+	w := &RBACWatcher{
+		kubeInformers:    informers.NewSharedInformerFactory(kc, 0),
+		projectInformers: projectinformers.NewSharedInformerFactory(pc, 0),
+		namespaces:       map[string]uint64{},
+		changes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "opa_openshift_rbac_changes_total",
+			Help: "Counts the number of observed changes to RBAC objects invalidating cached decisions.",
+		}, []string{"resource"}),
+	}
+
+	rbac := w.kubeInformers.Rbac().V1()
+
+	for resource, informer := range map[string]cache.SharedIndexInformer{
+		"roles":               rbac.Roles().Informer(),
+		"rolebindings":        rbac.RoleBindings().Informer(),
+		"clusterroles":        rbac.ClusterRoles().Informer(),
+		"clusterrolebindings": rbac.ClusterRoleBindings().Informer(),
+		resourceProjects:      w.projectInformers.Project().V1().Projects().Informer(),
+	} {
+		reg, err := informer.AddEventHandler(w.handler(resource))
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch %s: %w", resource, err)
+		}
+
+		w.synced = append(w.synced, reg.HasSynced)
+	}
+
+	if r != nil {
+		r.MustRegister(w.changes)
+	}
+
+	return w, nil
+}
+
+// Run watches the RBAC objects until the context is done. Generations are returned
+// once the initial list of all objects completed.
+func (w *RBACWatcher) Run(ctx context.Context) error {
+	w.kubeInformers.Start(ctx.Done())
+	w.projectInformers.Start(ctx.Done())
+
+	go w.WaitForCacheSync(ctx)
+
+	<-ctx.Done()
+
+	w.kubeInformers.Shutdown()
+	w.projectInformers.Shutdown()
+
+	return nil
+}
+
+// WaitForCacheSync waits until the RBAC objects were listed and taken into account
+// or the context is done, reporting whether all of them were taken into account.
+func (w *RBACWatcher) WaitForCacheSync(ctx context.Context) bool {
+	if !cache.WaitForCacheSync(ctx.Done(), w.synced...) {
+		return false
+	}
+
+	w.ready.Store(true)
+
+	return true
+}
+
+// ClusterGeneration returns the version of the cluster-scoped RBAC objects, or
+// an empty string until the initial list of all objects completed.
+func (w *RBACWatcher) ClusterGeneration() string {
+	if !w.ready.Load() {
+		return ""
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return strconv.FormatUint(w.cluster, 16)
+}
+
+// Generation returns the version of the cluster-scoped RBAC objects and of the
+// namespaced ones in the given namespaces, or in all namespaces when none are given.
+// It returns an empty string until the initial list of all objects completed.
+func (w *RBACWatcher) Generation(namespaces []string) string {
+	if !w.ready.Load() {
+		return ""
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	ns := w.allNamespaces
+	if len(namespaces) > 0 {
+		ns = 0
+		for _, n := range namespaces {
+			ns ^= w.namespaces[n]
+		}
+	}
+
+	return strconv.FormatUint(w.cluster, 16) + "." + strconv.FormatUint(ns, 16)
+}
+
+func (w *RBACWatcher) handler(resource string) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			w.toggle(resource, obj, !isInInitialList)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			o, oldOK := oldObj.(metav1.Object)
+			n, newOK := newObj.(metav1.Object)
+
+			if oldOK && newOK && o.GetResourceVersion() == n.GetResourceVersion() {
+				return
+			}
+
+			w.toggle(resource, oldObj, false)
+			w.toggle(resource, newObj, true)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+
+			w.toggle(resource, obj, true)
+		},
+	}
+}
+
+// toggle adds the object to or removes it from the digest of its scope. Both are
+// the same operation, so an update removes the old version and adds the new one.
+// Projects belong to the scope of their namespace, so that creating or deleting a
+// namespace does not invalidate the decisions of all other namespaces. Changes
+// are counted unless they are part of the initial list of objects.
+func (w *RBACWatcher) toggle(resource string, obj interface{}, count bool) {
+	o, ok := obj.(metav1.Object)
+	if !ok {
+		return
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(string(o.GetUID()) + "/" + o.GetResourceVersion()))
+	digest := h.Sum64()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ns := o.GetNamespace()
+	if resource == resourceProjects {
+		ns = o.GetName()
+	}
+
+	if ns != "" {
+		w.namespaces[ns] ^= digest
+		w.allNamespaces ^= digest
+
+		if w.namespaces[ns] == 0 {
+			delete(w.namespaces, ns)
+		}
+	} else {
+		w.cluster ^= digest
+	}
+
+	if count {
+		w.changes.WithLabelValues(resource).Inc()
+	}
+}
@@ -0,0 +1,150 @@
+package openshift
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	projectv1 "github.com/openshift/api/project/v1"
+	projectfake "github.com/openshift/client-go/project/clientset/versioned/fake"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func objectMeta(namespace, name, resourceVersion string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace:       namespace,
+		Name:            name,
+		UID:             types.UID(namespace + "/" + name),
+		ResourceVersion: resourceVersion,
+	}
+}
+
+func TestRBACWatcher_Generations(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	kc := fake.NewClientset(
+		&rbacv1.RoleBinding{ObjectMeta: objectMeta("ns-a", "view", "1")},
+		&rbacv1.ClusterRole{ObjectMeta: objectMeta("", "logs-reader", "1")},
+	)
+	pc := projectfake.NewClientset(
+		&projectv1.Project{ObjectMeta: objectMeta("", "ns-a", "1")},
+	)
+
+	w, err := newRBACWatcher(kc, pc, nil)
+	require.NoError(t, err)
+
+	go func() { _ = w.Run(ctx) }()
+
+	require.True(t, w.WaitForCacheSync(ctx))
+
+	cluster, nsA, nsB, all := w.ClusterGeneration(), w.Generation([]string{"ns-a"}), w.Generation([]string{"ns-b"}), w.Generation(nil)
+	require.NotEqual(t, nsA, nsB)
+	require.Equal(t, nsA, all)
+
+	changed := func(before string, current func() string) func() bool {
+		return func() bool { return current() != before }
+	}
+
+	// A namespaced change only affects the generations covering the namespace.
+	_, err = kc.RbacV1().RoleBindings("ns-b").Create(ctx, &rbacv1.RoleBinding{ObjectMeta: objectMeta("ns-b", "edit", "2")}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, changed(nsB, func() string { return w.Generation([]string{"ns-b"}) }), time.Second, 10*time.Millisecond)
+	require.Equal(t, nsA, w.Generation([]string{"ns-a"}))
+	require.Equal(t, cluster, w.ClusterGeneration())
+	require.NotEqual(t, all, w.Generation(nil))
+
+	// A cluster-scoped change affects all generations.
+	nsA = w.Generation([]string{"ns-a"})
+
+	_, err = kc.RbacV1().ClusterRoles().Update(ctx, &rbacv1.ClusterRole{ObjectMeta: objectMeta("", "logs-reader", "3")}, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, changed(cluster, w.ClusterGeneration), time.Second, 10*time.Millisecond)
+	require.NotEqual(t, nsA, w.Generation([]string{"ns-a"}))
+
+	// Deleting the object again restores the previous generation.
+	nsA = w.Generation([]string{"ns-a"})
+
+	err = kc.RbacV1().RoleBindings("ns-b").Delete(ctx, "edit", metav1.DeleteOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return w.Generation(nil) == nsA }, time.Second, 10*time.Millisecond)
+
+	// A Project change only affects the generations covering its namespace.
+	cluster, nsA, nsB = w.ClusterGeneration(), w.Generation([]string{"ns-a"}), w.Generation([]string{"ns-b"})
+
+	err = pc.ProjectV1().Projects().Delete(ctx, "ns-a", metav1.DeleteOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, changed(nsA, func() string { return w.Generation([]string{"ns-a"}) }), time.Second, 10*time.Millisecond)
+	require.Equal(t, cluster, w.ClusterGeneration())
+	require.Equal(t, nsB, w.Generation([]string{"ns-b"}))
+}
+
+func TestRBACWatcher_InitialSync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	kc := fake.NewClientset(
+		&rbacv1.RoleBinding{ObjectMeta: objectMeta("ns-a", "view", "1")},
+		&rbacv1.ClusterRole{ObjectMeta: objectMeta("", "logs-reader", "1")},
+	)
+
+	reg := prometheus.NewRegistry()
+
+	w, err := newRBACWatcher(kc, projectfake.NewClientset(), reg)
+	require.NoError(t, err)
+
+	// No generations are returned before the initial list completed.
+	require.Empty(t, w.ClusterGeneration())
+	require.Empty(t, w.Generation(nil))
+
+	go func() { _ = w.Run(ctx) }()
+
+	require.Eventually(t, func() bool { return w.Generation(nil) != "" }, time.Second, 10*time.Millisecond)
+	require.NotEmpty(t, w.ClusterGeneration())
+
+	// The objects of the initial list are not counted as changes.
+	require.Equal(t, 0, testutil.CollectAndCount(reg, "opa_openshift_rbac_changes_total"))
+
+	_, err = kc.RbacV1().RoleBindings("ns-a").Create(ctx, &rbacv1.RoleBinding{ObjectMeta: objectMeta("ns-a", "edit", "2")}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(w.changes.WithLabelValues("rolebindings")) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRBACWatcher_SameObjectsSameGeneration(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	generation := func() string {
+		w, err := newRBACWatcher(
+			fake.NewClientset(
+				&rbacv1.RoleBinding{ObjectMeta: objectMeta("ns-a", "view", "1")},
+				&rbacv1.ClusterRoleBinding{ObjectMeta: objectMeta("", "admins", "4")},
+			),
+			projectfake.NewClientset(),
+			nil,
+		)
+		require.NoError(t, err)
+
+		go func() { _ = w.Run(ctx) }()
+
+		require.True(t, w.WaitForCacheSync(ctx))
+
+		return w.Generation(nil)
+	}
+
+	// Replicas watching the same objects agree on the generation.
+	require.Equal(t, generation(), generation())
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	stdtls "crypto/tls"
 	"crypto/x509"
+	"fmt"
 	stdlog "log"
 	"net/http"
 	"os"
@@ -18,13 +19,19 @@ import (
 	"github.com/metalmatze/signal/healthcheck"
 	"github.com/metalmatze/signal/internalserver"
 	"github.com/metalmatze/signal/server/signalhttp"
+	"github.com/observatorium/opa-openshift/internal/audit"
+	"github.com/observatorium/opa-openshift/internal/authorizer"
 	"github.com/observatorium/opa-openshift/internal/cache"
 	"github.com/observatorium/opa-openshift/internal/config"
+	"github.com/observatorium/opa-openshift/internal/decisionlog"
 	"github.com/observatorium/opa-openshift/internal/handler"
 	"github.com/observatorium/opa-openshift/internal/instrumentation"
+	"github.com/observatorium/opa-openshift/internal/openshift"
 	"github.com/oklog/run"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/version"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"k8s.io/component-base/cli/flag"
 )
 
@@ -72,28 +79,190 @@ func main() {
 	rti := instrumentation.NewRoundTripperInstrumenter(reg)
 	healthchecks := healthcheck.NewMetricsHandler(healthcheck.NewHandler(), reg)
 
-	var mc cache.Cacher
+	var tp trace.TracerProvider = noop.NewTracerProvider()
 
-	if len(cfg.Memcached.Servers) > 0 {
+	if cfg.Tracing.Endpoint != "" {
+		sdktp, err := instrumentation.NewTracerProvider(
+			context.Background(), cfg.Tracing.ServiceName,
+			cfg.Tracing.Endpoint, cfg.Tracing.Insecure, cfg.Tracing.SamplingRatio,
+		)
+		if err != nil {
+			stdlog.Fatalf("failed to create tracer provider: %v", err)
+		}
+
+		defer func() { _ = sdktp.Shutdown(context.Background()) }()
+
+		tp = sdktp
+	}
+
+	serverKeyPair, err := config.NewKeyPair(cfg.TLS.ServerCertFile, cfg.TLS.ServerKeyFile)
+	if err != nil {
+		stdlog.Fatal(err)
+	}
+
+	internalKeyPair, err := config.NewKeyPair(cfg.TLS.InternalServerCertFile, cfg.TLS.InternalServerKeyFile)
+	if err != nil {
+		stdlog.Fatal(err)
+	}
+
+	reloader := config.NewReloader(log.With(logger, "component", "reloader"), cfg, reg, serverKeyPair, internalKeyPair)
+
+	var mc cache.CacherWithMetrics
+
+	remoteCache := len(cfg.Memcached.Servers) > 0 || len(cfg.Redis.Addrs) > 0
+
+	switch {
+	case len(cfg.Memcached.Servers) > 0:
 		mc = cache.NewMemached(context.Background(), cfg.Memcached.Interval, cfg.Memcached.Expire, cfg.Memcached.Servers...)
-	} else {
-		mc = cache.NewInMemoryCache(cfg.Memcached.Expire)
+	case len(cfg.Redis.Addrs) > 0:
+		opts, err := redisOptions(cfg)
+		if err != nil {
+			stdlog.Fatalf("failed to configure redis: %v", err)
+		}
+
+		mc = cache.NewRedis(opts)
+	default:
+		mc = cache.NewInMemoryCache(cfg.Memcached.Expire, cfg.Cache.MaxEntries, cfg.Cache.MaxBytes)
 	}
 
-	if cacheMetrics, ok := mc.(cache.CacherWithMetrics); ok {
-		reg.MustRegister(cacheMetrics)
+	// The backend without encryption and L1 tier, shared by all replicas.
+	backend := mc
+
+	if cfg.Cache.EncryptionKeyFile != "" && remoteCache {
+		keyring, err := cache.LoadKeyring(cfg.Cache.EncryptionKeyFile)
+		if err != nil {
+			stdlog.Fatalf("failed to load cache encryption keys: %v", err)
+		}
+
+		mc = cache.NewEncrypted(log.With(logger, "component", "cache"), mc, keyring)
+	}
+
+	if cfg.Cache.L1TTL > 0 && remoteCache {
+		mc = cache.NewTiered(mc, cfg.Cache.L1TTL, cfg.Cache.L1Capacity)
 	}
 
+	var (
+		index      *cache.Indexed
+		adminToken string
+		subjects   *authorizer.SubjectHasher
+		epochs     *cache.Epochs
+	)
+
+	if cfg.Server.AdminTokenFile != "" {
+		b, err := os.ReadFile(cfg.Server.AdminTokenFile)
+		if err != nil {
+			stdlog.Fatalf("failed to read admin token file: %v", err)
+		}
+
+		adminToken = strings.TrimSpace(string(b))
+		if adminToken == "" {
+			stdlog.Fatalf("admin token file %s is empty", cfg.Server.AdminTokenFile)
+		}
+
+		b, err = os.ReadFile(cfg.Cache.SubjectKeyFile)
+		if err != nil {
+			stdlog.Fatalf("failed to read subject key file: %v", err)
+		}
+
+		subjectKey := strings.TrimSpace(string(b))
+		if subjectKey == "" {
+			stdlog.Fatalf("subject key file %s is empty", cfg.Cache.SubjectKeyFile)
+		}
+
+		// Cache keys carry a digest of the subject keyed by a secret of its own, so that
+		// the admin endpoints can look up the decisions of a subject.
+		subjects = authorizer.NewSubjectHasher([]byte(subjectKey))
+		// Purges bump epochs kept in the shared backend, so that they reach all replicas.
+		epochs = cache.NewEpochs(backend)
+		index = cache.NewIndexed(mc, epochs, cacheDefaultTTL(cfg), cfg.Cache.IndexCapacity)
+		mc = index
+	}
+
+	reg.MustRegister(mc)
+
 	wt := func(rt http.RoundTripper) http.RoundTripper {
-		return rti.NewRoundTripper("openshift", rt)
+		return instrumentation.NewTracingRoundTripper(tp, "openshift", rti.NewRoundTripper("openshift", rt))
 	}
 
+	pool, err := openshift.NewClientPool(
+		wt, cfg.KubeconfigPath,
+		cfg.OpenShift.ClientPoolSize, cfg.OpenShift.ClientPoolTTL,
+	)
+	if err != nil {
+		stdlog.Fatalf("failed to create openshift client pool: %v", err)
+	}
+
+	reg.MustRegister(pool)
+
 	p := path.Join(dataEndpoint, strings.ReplaceAll(cfg.Opa.Pkg, ".", "/"), cfg.Opa.Rule)
 	level.Info(logger).Log("msg", "configuring the OPA endpoint", "path", p) //nolint:errcheck
 
 	l := log.With(logger, "component", "authorizer")
 	m := http.NewServeMux()
-	m.HandleFunc(p, hi.NewHandler(prometheus.Labels{"handler": "data"}, handler.New(l, mc, wt, cfg)))
+	dedup := authorizer.NewDeduplicator(reg)
+	handlerOpts := []handler.Option{
+		handler.WithAuthorizerOptions(authorizer.WithDeduplicator(dedup), authorizer.WithSubjectHasher(subjects), authorizer.WithEpochs(epochs)),
+		handler.WithTracerProvider(tp),
+		handler.WithDecisionMetrics(handler.NewDecisionMetrics(reg)),
+		handler.WithConfig(reloader.Config),
+	}
+
+	if cfg.Cache.StaleWhileRevalidate > 0 {
+		rv := authorizer.NewRevalidator(reg, l,
+			cfg.Cache.StaleWhileRevalidate, cacheDefaultTTL(cfg), revalidateTimeout(cfg),
+		)
+
+		handlerOpts = append(handlerOpts, handler.WithAuthorizerOptions(authorizer.WithRevalidator(rv)))
+	}
+
+	if cfg.Audit.Sink != "" {
+		sink, err := newAuditSink(log.With(logger, "component", "audit"), &cfg.Audit)
+		if err != nil {
+			stdlog.Fatalf("failed to create audit sink: %v", err)
+		}
+
+		al := audit.NewLogger(log.With(logger, "component", "audit"), sink, cfg.Audit.SampleRate)
+		defer func() { _ = al.Close() }()
+
+		handlerOpts = append(handlerOpts, handler.WithAuditLogger(al))
+	}
+
+	var rbacWatcher *openshift.RBACWatcher
+
+	if cfg.Cache.RBACWatch {
+		rbacWatcher, err = openshift.NewRBACWatcher(wt, cfg.KubeconfigPath, reg)
+		if err != nil {
+			stdlog.Fatalf("failed to create RBAC watcher: %v", err)
+		}
+
+		handlerOpts = append(handlerOpts, handler.WithAuthorizerOptions(authorizer.WithGenerations(rbacWatcher)))
+	}
+
+	var dl *decisionlog.Logger
+
+	if cfg.DecisionLogs.URL != "" {
+		dl, err = decisionlog.New(log.With(logger, "component", "decisionlog"), http.DefaultClient, decisionlog.Options{
+			URL:           cfg.DecisionLogs.URL,
+			Labels:        decisionLogLabels(cfg),
+			BufferSize:    cfg.DecisionLogs.BufferSize,
+			BatchSize:     cfg.DecisionLogs.BatchSize,
+			FlushInterval: cfg.DecisionLogs.FlushInterval,
+			Timeout:       cfg.DecisionLogs.Timeout,
+			MaxRetries:    cfg.DecisionLogs.MaxRetries,
+			RetryBackoff:  cfg.DecisionLogs.RetryBackoff,
+			SpoolDir:      cfg.DecisionLogs.SpoolDir,
+			MaxSpoolFiles: cfg.DecisionLogs.MaxSpoolFiles,
+		})
+		if err != nil {
+			stdlog.Fatalf("failed to create decision logger: %v", err)
+		}
+
+		handlerOpts = append(handlerOpts, handler.WithDecisionLogger(dl))
+	}
+
+	m.Handle(p, instrumentation.NewTracingHandler(tp, "data",
+		hi.NewHandler(prometheus.Labels{"handler": "data"}, handler.New(l, mc, pool, cfg, handlerOpts...)),
+	))
 
 	if cfg.Server.HealthcheckURL != "" {
 		minVer, err := flag.TLSVersion(cfg.TLS.MinVersion)
@@ -144,11 +313,43 @@ func main() {
 			close(sig)
 		})
 	}
+	{
+		g.Add(func() error {
+			pool.Start()
+
+			return nil
+		}, func(_ error) {
+			pool.Stop()
+		})
+	}
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			return reloader.Run(ctx)
+		}, func(_ error) {
+			cancel()
+		})
+	}
+	if rbacWatcher != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			return rbacWatcher.Run(ctx)
+		}, func(_ error) {
+			cancel()
+		})
+	}
+	if dl != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			return dl.Run(ctx)
+		}, func(_ error) {
+			cancel()
+		})
+	}
 	{
 		tlsConfig, err := config.NewServerConfig(
 			log.With(logger, "protocol", "HTTP"),
-			cfg.TLS.ServerCertFile,
-			cfg.TLS.ServerKeyFile,
+			serverKeyPair,
 			cfg.TLS.MinVersion,
 			cfg.TLS.CipherSuites,
 		)
@@ -182,8 +383,7 @@ func main() {
 	{
 		tlsConfig, err := config.NewServerConfig(
 			log.With(logger, "protocol", "HTTP"),
-			cfg.TLS.InternalServerCertFile,
-			cfg.TLS.InternalServerKeyFile,
+			internalKeyPair,
 			cfg.TLS.MinVersion,
 			cfg.TLS.CipherSuites,
 		)
@@ -200,6 +400,12 @@ func main() {
 			internalserver.WithPProf(),
 		)
 
+		if index != nil {
+			h.AddEndpoint(handler.AdminCachePrefix, "Inspect and purge cached decisions",
+				handler.NewAdmin(log.With(logger, "component", "admin"), index, subjects, adminToken),
+			)
+		}
+
 		s := http.Server{
 			Addr:      cfg.Server.ListenInternal,
 			Handler:   h,
@@ -224,3 +430,84 @@ func main() {
 		stdlog.Fatal(err)
 	}
 }
+
+// decisionLogLabels returns the labels identifying this instance in decision logs,
+// matching the labels set by OPA.
+func decisionLogLabels(cfg *config.Config) map[string]string {
+	id, _ := os.Hostname()
+
+	return map[string]string{
+		"id":      id,
+		"app":     cfg.Name,
+		"version": version.Version,
+	}
+}
+
+func newAuditSink(l log.Logger, cfg *config.AuditConfig) (audit.Sink, error) {
+	switch cfg.Sink {
+	case config.AuditSinkFile:
+		return audit.NewFileSink(cfg.FilePath, cfg.FileMaxSize, cfg.FileMaxBackups) //nolint:wrapcheck
+	case config.AuditSinkWebhook:
+		return audit.NewWebhookSink(l, cfg.WebhookURL, http.DefaultClient, cfg.WebhookTimeout, cfg.WebhookQueueSize), nil
+	default:
+		return audit.NewWriterSink(os.Stderr), nil
+	}
+}
+
+// cacheDefaultTTL returns the expiration the cache backend applies to decisions stored without a TTL.
+func cacheDefaultTTL(cfg *config.Config) time.Duration {
+	if len(cfg.Redis.Addrs) > 0 {
+		return cfg.Redis.Expire
+	}
+
+	return time.Duration(cfg.Memcached.Expire) * time.Second
+}
+
+// revalidateTimeout returns the timeout of a background revalidation, bounded
+// like the decision of a request unless decisions are unbounded.
+func revalidateTimeout(cfg *config.Config) time.Duration {
+	if cfg.OpenShift.DecisionTimeout > 0 {
+		return cfg.OpenShift.DecisionTimeout
+	}
+
+	return time.Minute
+}
+
+func redisOptions(cfg *config.Config) (cache.RedisOptions, error) {
+	opts := cache.RedisOptions{
+		Addrs:      cfg.Redis.Addrs,
+		MasterName: cfg.Redis.MasterName,
+		Cluster:    cfg.Redis.Cluster,
+		DB:         cfg.Redis.DB,
+		Username:   cfg.Redis.Username,
+		Timeout:    cfg.Redis.Timeout,
+		Expiration: cfg.Redis.Expire,
+	}
+
+	if cfg.Redis.PasswordFile != "" {
+		password, err := os.ReadFile(cfg.Redis.PasswordFile)
+		if err != nil {
+			return cache.RedisOptions{}, fmt.Errorf("failed to read password file: %w", err)
+		}
+
+		opts.Password = strings.TrimSpace(string(password))
+	}
+
+	if cfg.Redis.TLSEnabled {
+		tlsConfig, err := config.NewClientConfig(
+			cfg.Redis.TLSCAFile,
+			cfg.Redis.TLSCertFile,
+			cfg.Redis.TLSKeyFile,
+			cfg.Redis.TLSServerName,
+			cfg.Redis.TLSInsecureSkipVerify,
+			cfg.TLS.MinVersion,
+		)
+		if err != nil {
+			return cache.RedisOptions{}, fmt.Errorf("failed to create TLS config: %w", err)
+		}
+
+		opts.TLSConfig = tlsConfig
+	}
+
+	return opts, nil
+}